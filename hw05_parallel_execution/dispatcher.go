@@ -0,0 +1,210 @@
+package hw05parallelexecution
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+type job struct {
+	index    int
+	priority int
+	group    string
+	task     Task
+	done     func(err error)
+}
+
+// dispatcher picks the next job to run honoring priorities, group limits and the rate limit.
+type dispatcher struct {
+	tasks     <-chan Task
+	lookahead int
+	opts      *options
+	limiter   *tokenBucket
+
+	index     int
+	exhausted bool
+	pending   int
+	queues    map[string]*jobQueue
+
+	mu      sync.Mutex
+	running map[string]int
+	wake    chan struct{}
+}
+
+func newDispatcher(tasks <-chan Task, lookahead int, opts *options) *dispatcher {
+	d := &dispatcher{
+		tasks:     tasks,
+		lookahead: lookahead,
+		opts:      opts,
+		queues:    make(map[string]*jobQueue),
+		running:   make(map[string]int),
+		wake:      make(chan struct{}, 1),
+	}
+	if opts.rateLimit > 0 {
+		d.limiter = newTokenBucket(opts.rateLimit, opts.burst)
+	}
+	return d
+}
+
+// next returns the next job to run, or false when there are no more tasks or ctx is done.
+func (d *dispatcher) next(ctx context.Context) (job, bool) {
+	j, ok := d.schedule(ctx)
+	if !ok {
+		return job{}, false
+	}
+	if d.limiter != nil && d.limiter.wait(ctx) != nil {
+		return job{}, false
+	}
+	return j, true
+}
+
+func (d *dispatcher) schedule(ctx context.Context) (job, bool) {
+	if d.opts.priority == nil && d.opts.group == nil {
+		select {
+		case <-ctx.Done():
+			return job{}, false
+		case t, ok := <-d.tasks:
+			if !ok {
+				return job{}, false
+			}
+			return d.newJob(t), true
+		}
+	}
+
+	for {
+		d.fill()
+		if j, ok := d.pick(); ok {
+			return j, true
+		}
+		if d.exhausted && d.pending == 0 {
+			return job{}, false
+		}
+
+		var tasks <-chan Task
+		if !d.exhausted && d.pending < d.lookahead {
+			tasks = d.tasks
+		}
+		select {
+		case <-ctx.Done():
+			return job{}, false
+		case <-d.wake:
+		case t, ok := <-tasks:
+			if !ok {
+				d.exhausted = true
+				continue
+			}
+			d.push(d.newJob(t))
+		}
+	}
+}
+
+// fill moves already available tasks to the pending queues without blocking.
+func (d *dispatcher) fill() {
+	for !d.exhausted && d.pending < d.lookahead {
+		select {
+		case t, ok := <-d.tasks:
+			if !ok {
+				d.exhausted = true
+				return
+			}
+			d.push(d.newJob(t))
+		default:
+			return
+		}
+	}
+}
+
+func (d *dispatcher) newJob(t Task) job {
+	j := job{index: d.index, task: t}
+	d.index++
+	if d.opts.progress != nil {
+		d.opts.progress.taskReceived()
+	}
+	if d.opts.priority != nil {
+		j.priority = d.opts.priority(j.index)
+	}
+	if d.opts.group != nil {
+		j.group = d.opts.group(j.index)
+	}
+	return j
+}
+
+func (d *dispatcher) push(j job) {
+	q, ok := d.queues[j.group]
+	if !ok {
+		q = &jobQueue{}
+		d.queues[j.group] = q
+	}
+	heap.Push(q, j)
+	d.pending++
+}
+
+// pick takes the most prioritized pending job among the groups that are below their limits.
+func (d *dispatcher) pick() (job, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var best *jobQueue
+	for group, q := range d.queues {
+		if q.Len() == 0 || !d.available(group) {
+			continue
+		}
+		if best == nil || before((*q)[0], (*best)[0]) {
+			best = q
+		}
+	}
+	if best == nil {
+		return job{}, false
+	}
+
+	j := heap.Pop(best).(job)
+	d.pending--
+	d.running[j.group]++
+	return j, true
+}
+
+func (d *dispatcher) available(group string) bool {
+	limit, ok := d.opts.groupLimits[group]
+	return !ok || limit <= 0 || d.running[group] < limit
+}
+
+// release marks the job as finished, freeing a slot of its group.
+func (d *dispatcher) release(j job) {
+	if d.opts.group == nil {
+		return
+	}
+
+	d.mu.Lock()
+	d.running[j.group]--
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func before(a, b job) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.index < b.index
+}
+
+// jobQueue is a heap of jobs ordered by priority and then by index.
+type jobQueue []job
+
+func (q jobQueue) Len() int           { return len(q) }
+func (q jobQueue) Less(i, j int) bool { return before(q[i], q[j]) }
+func (q jobQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *jobQueue) Push(x interface{}) {
+	*q = append(*q, x.(job))
+}
+
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	j := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return j
+}
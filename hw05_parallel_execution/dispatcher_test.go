@@ -0,0 +1,125 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestDispatcher(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("priorities", func(t *testing.T) {
+		tasksCount := 10
+		tasks := make([]Task, 0, tasksCount)
+
+		mu := sync.Mutex{}
+		order := make([]int, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			i := i
+			tasks = append(tasks, func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, i)
+				return nil
+			})
+		}
+
+		err := Run(tasks, 1, 1, WithPriority(func(task int) int {
+			return task % 2
+		}))
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 3, 5, 7, 9, 0, 2, 4, 6, 8}, order)
+	})
+
+	t.Run("group limits", func(t *testing.T) {
+		tasksCount := 30
+		tasks := make([]Task, 0, tasksCount)
+
+		var running, maxRunning, runTasksCount int32
+		for i := 0; i < tasksCount; i++ {
+			i := i
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				if i%3 != 0 {
+					return nil
+				}
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					prev := atomic.LoadInt32(&maxRunning)
+					if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+		}
+
+		group := func(task int) string {
+			if task%3 == 0 {
+				return "api"
+			}
+			return "local"
+		}
+		err := Run(tasks, 10, 1, WithGroupLimits(group, map[string]int{"api": 2}))
+		require.NoError(t, err)
+		require.Equal(t, int32(tasksCount), runTasksCount)
+		require.LessOrEqual(t, maxRunning, int32(2))
+	})
+
+	t.Run("group limits in stream", func(t *testing.T) {
+		tasksCh := make(chan Task)
+		var runTasksCount int32
+		go func() {
+			defer close(tasksCh)
+			for i := 0; i < 20; i++ {
+				tasksCh <- func() error {
+					atomic.AddInt32(&runTasksCount, 1)
+					time.Sleep(time.Millisecond)
+					return nil
+				}
+			}
+		}()
+
+		err := RunStream(context.Background(), tasksCh, 4, 1, WithGroupLimits(func(task int) string {
+			return "all"
+		}, map[string]int{"all": 1}))
+		require.NoError(t, err)
+		require.Equal(t, int32(20), runTasksCount)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		tasksCount := 10
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				return nil
+			})
+		}
+
+		start := time.Now()
+		err := Run(tasks, 5, 1, WithRateLimit(100, 5))
+		elapsed := time.Since(start)
+		require.NoError(t, err)
+		// 5 tasks start at once, the rest take 10ms each.
+		require.GreaterOrEqual(t, elapsed, 45*time.Millisecond)
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("context canceled", func(t *testing.T) {
+		b := newTokenBucket(1, 1)
+		require.NoError(t, b.wait(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, b.wait(ctx), context.Canceled)
+	})
+}
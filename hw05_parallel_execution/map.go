@@ -0,0 +1,57 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrTaskNotStarted = errors.New("task not started")
+
+// Result holds the value and the error returned for a single input of Map.
+type Result[R any] struct {
+	Value   R
+	Err     error
+	Retries int
+}
+
+// Map applies fn to inputs in workers goroutines and stops its work when receiving maxErrors errors.
+// Results are returned in input order; inputs that were never processed get ErrTaskNotStarted.
+// The context passed to fn is canceled when ctx is done or the work stops.
+func Map[T, R any](
+	ctx context.Context,
+	inputs []T,
+	fn func(context.Context, T) (R, error),
+	workers, maxErrors int,
+	opts ...Option,
+) ([]Result[R], error) {
+	if len(inputs) == 0 {
+		return nil, ErrEmptyTaskList
+	}
+	o := newOptions(opts)
+	if err := validate(workers, maxErrors, o); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]Result[R], len(inputs))
+	tasks := make([]Task, len(inputs))
+	for i := range inputs {
+		i := i
+		results[i].Err = ErrTaskNotStarted
+		attempts := 0
+		tasks[i] = func() error {
+			var value R
+			err := safeCall(func() (err error) {
+				value, err = fn(runCtx, inputs[i])
+				return err
+			})
+			results[i] = Result[R]{Value: value, Err: err, Retries: attempts}
+			attempts++
+			return err
+		}
+	}
+
+	return results, executeWithCancel(runCtx, cancel, sliceSource(tasks), len(tasks), workers, maxErrors, o)
+}
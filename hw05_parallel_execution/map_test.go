@@ -0,0 +1,116 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMap(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("empty inputs", func(t *testing.T) {
+		results, err := Map(context.Background(), []int{}, func(_ context.Context, v int) (int, error) {
+			return v, nil
+		}, 1, 1)
+		require.ErrorIs(t, err, ErrEmptyTaskList)
+		require.Nil(t, results)
+	})
+
+	t.Run("results in input order", func(t *testing.T) {
+		inputs := make([]int, 50)
+		for i := range inputs {
+			inputs[i] = i
+		}
+
+		results, err := Map(context.Background(), inputs, func(_ context.Context, v int) (string, error) {
+			time.Sleep(time.Millisecond * time.Duration(rand.Intn(10)))
+			return strconv.Itoa(v), nil
+		}, 5, 1)
+		require.NoError(t, err)
+		require.Len(t, results, len(inputs))
+		for i, r := range results {
+			require.NoError(t, r.Err)
+			require.Equal(t, strconv.Itoa(i), r.Value)
+		}
+	})
+
+	t.Run("per-item errors", func(t *testing.T) {
+		inputs := []int{1, 2, 3, 4}
+		results, err := Map(context.Background(), inputs, func(_ context.Context, v int) (int, error) {
+			if v%2 == 0 {
+				return 0, fmt.Errorf("even value %d", v)
+			}
+			return v * 10, nil
+		}, 2, 10)
+		require.NoError(t, err)
+		require.Equal(t, 10, results[0].Value)
+		require.EqualError(t, results[1].Err, "even value 2")
+		require.Equal(t, 30, results[2].Value)
+		require.EqualError(t, results[3].Err, "even value 4")
+	})
+
+	t.Run("errors limit exceeded", func(t *testing.T) {
+		inputs := make([]int, 50)
+		var runCount int32
+
+		workersCount := 5
+		maxErrorsCount := 10
+		results, err := Map(context.Background(), inputs, func(_ context.Context, v int) (int, error) {
+			atomic.AddInt32(&runCount, 1)
+			time.Sleep(time.Millisecond * time.Duration(rand.Intn(10)))
+			return 0, errors.New("failed")
+		}, workersCount, maxErrorsCount)
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.LessOrEqual(t, runCount, int32(workersCount+maxErrorsCount), "extra tasks were started")
+
+		var notStarted int
+		for _, r := range results {
+			if errors.Is(r.Err, ErrTaskNotStarted) {
+				notStarted++
+			}
+		}
+		require.Equal(t, len(inputs)-int(runCount), notStarted)
+	})
+
+	t.Run("running tasks see the stop", func(t *testing.T) {
+		started := make(chan struct{})
+		results, err := Map(context.Background(), []int{0, 1}, func(ctx context.Context, v int) (int, error) {
+			if v == 0 {
+				<-started
+				return 0, errors.New("failed")
+			}
+			close(started)
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(time.Second):
+				return v, nil
+			}
+		}, 2, 1)
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.ErrorIs(t, results[1].Err, context.Canceled)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		inputs := make([]int, 20)
+
+		results, err := Map(ctx, inputs, func(ctx context.Context, v int) (int, error) {
+			cancel()
+			<-ctx.Done()
+			return 0, nil
+		}, 1, 1)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[len(results)-1].Err, ErrTaskNotStarted)
+	})
+}
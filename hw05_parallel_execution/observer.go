@@ -0,0 +1,89 @@
+package hw05parallelexecution
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TaskEvent describes a task that started or finished.
+type TaskEvent struct {
+	Task     int
+	Retries  int
+	Duration time.Duration
+	Err      error
+}
+
+// Observer receives events of the tasks, its methods are called concurrently from the workers.
+type Observer interface {
+	TaskStarted(event TaskEvent)
+	TaskFinished(event TaskEvent)
+	TaskFailed(event TaskEvent)
+}
+
+// Snapshot is the state of the tasks at some moment, Done counts failed tasks too.
+type Snapshot struct {
+	Queued  int
+	Running int
+	Done    int
+	Failed  int
+}
+
+// Progress tracks the state of the run it is passed to with WithProgress.
+// It must not be shared by simultaneous runs.
+type Progress struct {
+	mu    sync.Mutex
+	tasks <-chan Task
+
+	received int64
+	running  int64
+	done     int64
+	failed   int64
+}
+
+// Snapshot returns the current state of the tracked run.
+func (p *Progress) Snapshot() Snapshot {
+	p.mu.Lock()
+	buffered := len(p.tasks)
+	p.mu.Unlock()
+
+	return Snapshot{
+		Queued:  buffered + int(atomic.LoadInt64(&p.received)),
+		Running: int(atomic.LoadInt64(&p.running)),
+		Done:    int(atomic.LoadInt64(&p.done)),
+		Failed:  int(atomic.LoadInt64(&p.failed)),
+	}
+}
+
+func (p *Progress) reset(tasks <-chan Task) {
+	p.mu.Lock()
+	p.tasks = tasks
+	p.mu.Unlock()
+
+	atomic.StoreInt64(&p.received, 0)
+	atomic.StoreInt64(&p.running, 0)
+	atomic.StoreInt64(&p.done, 0)
+	atomic.StoreInt64(&p.failed, 0)
+}
+
+func (p *Progress) taskReceived() {
+	atomic.AddInt64(&p.received, 1)
+}
+
+// taskDropped forgets a received task that is never going to run.
+func (p *Progress) taskDropped() {
+	atomic.AddInt64(&p.received, -1)
+}
+
+func (p *Progress) taskStarted() {
+	atomic.AddInt64(&p.received, -1)
+	atomic.AddInt64(&p.running, 1)
+}
+
+func (p *Progress) taskFinished(err error) {
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+	}
+	atomic.AddInt64(&p.done, 1)
+	atomic.AddInt64(&p.running, -1)
+}
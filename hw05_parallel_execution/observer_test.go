@@ -0,0 +1,96 @@
+package hw05parallelexecution
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	started  []int
+	finished []TaskEvent
+	failed   []TaskEvent
+}
+
+func (o *recordingObserver) TaskStarted(event TaskEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = append(o.started, event.Task)
+}
+
+func (o *recordingObserver) TaskFinished(event TaskEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finished = append(o.finished, event)
+}
+
+func (o *recordingObserver) TaskFailed(event TaskEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.failed = append(o.failed, event)
+}
+
+func TestObserver(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("task events", func(t *testing.T) {
+		tasksCount := 10
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			i := i
+			tasks = append(tasks, func() error {
+				time.Sleep(time.Millisecond)
+				if i%5 == 0 {
+					return errors.New("failed")
+				}
+				return nil
+			})
+		}
+
+		observer := &recordingObserver{}
+		err := Run(tasks, 3, 3, WithObserver(observer))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, observer.started)
+		require.Len(t, observer.finished, 8)
+		require.Len(t, observer.failed, 2)
+		for _, event := range append(observer.finished, observer.failed...) {
+			require.GreaterOrEqual(t, event.Duration, time.Millisecond)
+		}
+		for _, event := range observer.failed {
+			require.Error(t, event.Err)
+			require.Zero(t, event.Task%5)
+		}
+	})
+
+	t.Run("progress snapshot", func(t *testing.T) {
+		tasksCount := 10
+		release := make(chan struct{})
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				<-release
+				return nil
+			})
+		}
+
+		progress := &Progress{}
+		errCh := make(chan error)
+		go func() {
+			errCh <- Run(tasks, 2, 1, WithProgress(progress))
+		}()
+
+		require.Eventually(t, func() bool {
+			s := progress.Snapshot()
+			return s.Running == 2 && s.Queued == 8
+		}, time.Second, time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-errCh)
+		require.Equal(t, Snapshot{Done: tasksCount}, progress.Snapshot())
+	})
+}
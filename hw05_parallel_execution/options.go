@@ -0,0 +1,79 @@
+package hw05parallelexecution
+
+// Option configures the behavior of Run and Map.
+type Option func(*options)
+
+type options struct {
+	retry       *RetryPolicy
+	rateLimit   float64
+	burst       int
+	priority    func(task int) int
+	group       func(task int) string
+	groupLimits map[string]int
+	stop        StopPolicy
+	observer    Observer
+	progress    *Progress
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRetry makes failed tasks run again according to the policy.
+// Only the final failure of a task counts toward the errors limit.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = &policy
+	}
+}
+
+// WithRateLimit starts no more than perSecond tasks per second allowing bursts of up to burst tasks.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(o *options) {
+		o.rateLimit = perSecond
+		o.burst = burst
+	}
+}
+
+// WithPriority makes tasks with greater priority start first, tasks of equal priority keep their order.
+// The task index is its position in the slice, or in the order of receiving for RunStream,
+// where only tasks already received are reordered.
+func WithPriority(priority func(task int) int) Option {
+	return func(o *options) {
+		o.priority = priority
+	}
+}
+
+// WithGroupLimits bounds the number of simultaneously running tasks of every group in limits.
+// Groups missing in limits or with non-positive limits are not bounded.
+func WithGroupLimits(group func(task int) string, limits map[string]int) Option {
+	return func(o *options) {
+		o.group = group
+		o.groupLimits = limits
+	}
+}
+
+// WithStopPolicy stops the work when the policy fires instead of after receiving m errors.
+func WithStopPolicy(policy StopPolicy) Option {
+	return func(o *options) {
+		o.stop = policy
+	}
+}
+
+// WithObserver sends the events of every task to the observer.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// WithProgress makes the progress track the state of the run.
+func WithProgress(progress *Progress) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
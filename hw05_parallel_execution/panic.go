@@ -0,0 +1,26 @@
+package hw05parallelexecution
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned instead of the error of a task that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// safeCall runs the task and converts its panic into PanicError.
+func safeCall(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return task()
+}
@@ -0,0 +1,64 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("panics count toward the limit", func(t *testing.T) {
+		tasksCount := 20
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				panic("boom")
+			})
+		}
+
+		workersCount := 4
+		maxErrorsCount := 3
+		err := Run(tasks, workersCount, maxErrorsCount)
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.LessOrEqual(t, runTasksCount, int32(workersCount+maxErrorsCount), "extra tasks were started")
+	})
+
+	t.Run("panics below the limit", func(t *testing.T) {
+		var runTasksCount int32
+		tasks := []Task{
+			func() error {
+				panic("boom")
+			},
+			func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				return nil
+			},
+		}
+
+		err := Run(tasks, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, int32(1), runTasksCount)
+	})
+
+	t.Run("panic error in map results", func(t *testing.T) {
+		results, err := Map(context.Background(), []int{0, 1}, func(_ context.Context, v int) (int, error) {
+			return 10 / v, nil
+		}, 2, 2)
+		require.NoError(t, err)
+		require.Equal(t, 10, results[1].Value)
+
+		var panicErr *PanicError
+		require.True(t, errors.As(results[0].Err, &panicErr))
+		require.Contains(t, panicErr.Error(), "divide by zero")
+		require.NotEmpty(t, panicErr.Stack)
+	})
+}
@@ -0,0 +1,232 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("pool is closed")
+
+// Future is the result of a task submitted to Pool.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Done is closed when the task is finished.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err waits for the task to finish and returns its error.
+func (f *Future) Err() error {
+	<-f.done
+	return f.err
+}
+
+func (f *Future) resolve(err error) {
+	f.err = err
+	close(f.done)
+}
+
+// Pool runs submitted tasks in a resizable set of long-lived workers.
+// Errors of the tasks never stop the pool, so WithStopPolicy and the dispatcher options
+// (WithRateLimit, WithPriority, WithGroupLimits) have no effect on it.
+type Pool struct {
+	executor *executor
+	ctx      context.Context
+	jobsCh   chan job
+	workers  sync.WaitGroup
+	tasks    sync.WaitGroup
+
+	// sending is held by Submit while it waits for a place in the queue,
+	// so forced Shutdown does not leave tasks in the queue after draining it.
+	sending sync.RWMutex
+
+	mu     sync.Mutex
+	closed bool
+	index  int
+	quits  []chan struct{}
+}
+
+// NewPool starts n workers and lets up to queueSize submitted tasks wait for a free worker.
+func NewPool(n, queueSize int, opts ...Option) (*Pool, error) {
+	if n <= 0 {
+		return nil, ErrWrongNumberOfGoroutines
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	o := newOptions(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		executor: &executor{
+			opts:       o,
+			dispatcher: newDispatcher(nil, 0, &options{}),
+			stop:       IgnoreErrors(),
+			cancel:     cancel,
+		},
+		ctx:    ctx,
+		jobsCh: make(chan job, queueSize),
+	}
+	if o.progress != nil {
+		o.progress.reset(nil)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grow(n)
+	return p, nil
+}
+
+// Submit queues the task, blocking while the queue is full.
+// The future of a task submitted to a closed pool fails with ErrPoolClosed.
+func (p *Pool) Submit(task Task) *Future {
+	future := newFuture()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		future.resolve(ErrPoolClosed)
+		return future
+	}
+	j := job{index: p.index, task: task, done: func(err error) {
+		future.resolve(err)
+		p.tasks.Done()
+	}}
+	p.index++
+	p.tasks.Add(1)
+	p.mu.Unlock()
+
+	if p.executor.opts.progress != nil {
+		p.executor.opts.progress.taskReceived()
+	}
+
+	p.sending.RLock()
+	defer p.sending.RUnlock()
+	if p.ctx.Err() != nil {
+		p.reject(j)
+		return future
+	}
+	select {
+	case <-p.ctx.Done():
+		p.reject(j)
+	case p.jobsCh <- j:
+	}
+	return future
+}
+
+// reject fails the received job that is not going to run with ErrPoolClosed.
+func (p *Pool) reject(j job) {
+	if p.executor.opts.progress != nil {
+		p.executor.opts.progress.taskDropped()
+	}
+	j.done(ErrPoolClosed)
+}
+
+// Wait blocks until all submitted tasks are finished.
+func (p *Pool) Wait() {
+	p.tasks.Wait()
+}
+
+// Resize changes the number of workers, removed workers finish their current tasks first.
+func (p *Pool) Resize(n int) error {
+	if n <= 0 {
+		return ErrWrongNumberOfGoroutines
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	if n > len(p.quits) {
+		p.grow(n - len(p.quits))
+		return nil
+	}
+	for _, quit := range p.quits[n:] {
+		close(quit)
+	}
+	p.quits = p.quits[:n]
+	return nil
+}
+
+// Size returns the current number of workers.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.quits)
+}
+
+// Shutdown stops accepting tasks and waits for the submitted ones to finish.
+// When ctx is done first, the tasks still waiting in the queue fail with ErrPoolClosed,
+// running tasks are left to finish in background and ctx error is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		p.tasks.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.executor.cancel()
+	}
+
+	p.mu.Lock()
+	for _, quit := range p.quits {
+		close(quit)
+	}
+	p.quits = nil
+	p.mu.Unlock()
+
+	if err != nil {
+		p.drain()
+		return err
+	}
+	p.executor.cancel()
+	p.workers.Wait()
+	return nil
+}
+
+// grow starts n more workers, p.mu must be held.
+func (p *Pool) grow(n int) {
+	p.workers.Add(n)
+	for i := 0; i < n; i++ {
+		quit := make(chan struct{})
+		p.quits = append(p.quits, quit)
+		go p.executor.worker(p.ctx, &p.workers, p.jobsCh, quit)
+	}
+}
+
+// drain fails the tasks left in the queue.
+func (p *Pool) drain() {
+	p.sending.Lock()
+	defer p.sending.Unlock()
+	for {
+		select {
+		case j := <-p.jobsCh:
+			p.reject(j)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("wrong number of goroutines", func(t *testing.T) {
+		_, err := NewPool(0, 1)
+		require.ErrorIs(t, err, ErrWrongNumberOfGoroutines)
+	})
+
+	t.Run("submit and wait", func(t *testing.T) {
+		p, err := NewPool(4, 10)
+		require.NoError(t, err)
+
+		var runTasksCount int32
+		errFailed := errors.New("failed")
+		futures := make([]*Future, 0, 20)
+		for batch := 0; batch < 2; batch++ {
+			for i := 0; i < 10; i++ {
+				i := i
+				futures = append(futures, p.Submit(func() error {
+					atomic.AddInt32(&runTasksCount, 1)
+					if i == 0 {
+						return errFailed
+					}
+					return nil
+				}))
+			}
+			p.Wait()
+			require.Equal(t, int32((batch+1)*10), atomic.LoadInt32(&runTasksCount))
+		}
+
+		for i, f := range futures {
+			if i%10 == 0 {
+				require.ErrorIs(t, f.Err(), errFailed)
+			} else {
+				require.NoError(t, f.Err())
+			}
+		}
+
+		require.NoError(t, p.Shutdown(context.Background()))
+		require.ErrorIs(t, p.Submit(func() error { return nil }).Err(), ErrPoolClosed)
+		require.ErrorIs(t, p.Shutdown(context.Background()), ErrPoolClosed)
+		require.ErrorIs(t, p.Resize(2), ErrPoolClosed)
+	})
+
+	t.Run("panic in task", func(t *testing.T) {
+		p, err := NewPool(1, 0)
+		require.NoError(t, err)
+
+		var panicErr *PanicError
+		require.True(t, errors.As(p.Submit(func() error { panic("boom") }).Err(), &panicErr))
+		require.NoError(t, p.Submit(func() error { return nil }).Err())
+		require.NoError(t, p.Shutdown(context.Background()))
+	})
+
+	t.Run("back-pressure", func(t *testing.T) {
+		p, err := NewPool(1, 1)
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		blocking := func() error {
+			<-release
+			return nil
+		}
+		p.Submit(blocking)
+		p.Submit(blocking)
+
+		submitted := make(chan struct{})
+		go func() {
+			p.Submit(blocking)
+			close(submitted)
+		}()
+
+		select {
+		case <-submitted:
+			t.Fatal("submit did not block on the full queue")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		<-submitted
+		require.NoError(t, p.Shutdown(context.Background()))
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		p, err := NewPool(1, 10)
+		require.NoError(t, err)
+		require.ErrorIs(t, p.Resize(0), ErrWrongNumberOfGoroutines)
+
+		var running int32
+		release := make(chan struct{})
+		for i := 0; i < 4; i++ {
+			p.Submit(func() error {
+				atomic.AddInt32(&running, 1)
+				<-release
+				return nil
+			})
+		}
+
+		require.NoError(t, p.Resize(4))
+		require.Equal(t, 4, p.Size())
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&running) == 4
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, p.Resize(2))
+		require.Equal(t, 2, p.Size())
+		close(release)
+		p.Wait()
+		require.NoError(t, p.Shutdown(context.Background()))
+	})
+
+	t.Run("shutdown timeout", func(t *testing.T) {
+		p, err := NewPool(1, 10)
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		running := p.Submit(func() error {
+			<-release
+			return nil
+		})
+		queued := p.Submit(func() error { return nil })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, p.Shutdown(ctx), context.DeadlineExceeded)
+		require.ErrorIs(t, queued.Err(), ErrPoolClosed)
+
+		close(release)
+		require.NoError(t, running.Err())
+		p.Wait()
+	})
+
+	t.Run("progress after shutdown timeout", func(t *testing.T) {
+		progress := &Progress{}
+		p, err := NewPool(1, 1, WithProgress(progress))
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		running := p.Submit(func() error {
+			<-release
+			return nil
+		})
+		require.Eventually(t, func() bool {
+			return progress.Snapshot().Running == 1
+		}, time.Second, time.Millisecond)
+		queued := p.Submit(func() error { return nil })
+		blocked := make(chan *Future)
+		go func() {
+			blocked <- p.Submit(func() error { return nil })
+		}()
+		require.Eventually(t, func() bool {
+			return progress.Snapshot().Queued == 2
+		}, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, p.Shutdown(ctx), context.DeadlineExceeded)
+		require.ErrorIs(t, queued.Err(), ErrPoolClosed)
+		require.ErrorIs(t, (<-blocked).Err(), ErrPoolClosed)
+
+		close(release)
+		require.NoError(t, running.Err())
+		p.Wait()
+		require.Equal(t, Snapshot{Done: 1}, progress.Snapshot())
+	})
+}
@@ -0,0 +1,51 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"time"
+)
+
+// tokenBucket allows rate events per second with bursts of up to burst events.
+// It is used by the dispatcher goroutine only and is not safe for concurrent use.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, blocking until it is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-b.tokens / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how failed tasks are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per task including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry, values below 1 mean 2.
+	Multiplier float64
+	// Jitter randomizes every delay by up to the given fraction of it, from 0 to 1.
+	Jitter float64
+	// Retryable reports whether the error is transient, nil means every error is.
+	Retryable func(err error) bool
+	// Report receives the number of retries made by the task with the given index and its final error.
+	Report func(task, retries int, err error)
+}
+
+// do runs the task until it succeeds, the attempts run out, the error is not retryable or ctx is done.
+// It returns the number of retries made and the last error of the task.
+func (p *RetryPolicy) do(ctx context.Context, task Task) (int, error) {
+	var retries int
+	for {
+		err := task()
+		if err == nil || retries+1 >= p.MaxAttempts || (p.Retryable != nil && !p.Retryable(err)) {
+			return retries, err
+		}
+
+		timer := time.NewTimer(p.backoff(retries))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, err
+		case <-timer.C:
+		}
+		retries++
+	}
+}
+
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += (rand.Float64()*2 - 1) * math.Min(p.Jitter, 1) * delay //nolint:gosec
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,108 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+var errTransient = errors.New("transient error")
+
+func TestRetry(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("transient failures do not count toward the limit", func(t *testing.T) {
+		tasksCount := 10
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			var calls int32
+			tasks = append(tasks, func() error {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					return errTransient
+				}
+				return nil
+			})
+		}
+
+		mu := sync.Mutex{}
+		reported := make(map[int]int)
+		err := Run(tasks, 3, 1, WithRetry(RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Jitter:         0.5,
+			Report: func(task, retries int, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported[task] = retries
+			},
+		}))
+		require.NoError(t, err)
+		require.Len(t, reported, tasksCount)
+		for _, retries := range reported {
+			require.Equal(t, 2, retries)
+		}
+	})
+
+	t.Run("final failures count toward the limit", func(t *testing.T) {
+		var calls int32
+		tasks := []Task{func() error {
+			atomic.AddInt32(&calls, 1)
+			return errTransient
+		}}
+
+		err := Run(tasks, 1, 1, WithRetry(RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.Equal(t, int32(4), calls)
+	})
+
+	t.Run("not retryable errors", func(t *testing.T) {
+		var calls int32
+		errPermanent := errors.New("permanent error")
+		tasks := []Task{func() error {
+			atomic.AddInt32(&calls, 1)
+			return errPermanent
+		}}
+
+		err := Run(tasks, 1, 1, WithRetry(RetryPolicy{
+			MaxAttempts: 4,
+			Retryable: func(err error) bool {
+				return errors.Is(err, errTransient)
+			},
+		}))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.Equal(t, int32(1), calls)
+	})
+
+	t.Run("retries in map results", func(t *testing.T) {
+		var calls int32
+		results, err := Map(context.Background(), []int{1}, func(_ context.Context, v int) (int, error) {
+			if atomic.AddInt32(&calls, 1) < 2 {
+				return 0, errTransient
+			}
+			return v, nil
+		}, 1, 1, WithRetry(RetryPolicy{MaxAttempts: 5}))
+		require.NoError(t, err)
+		require.Equal(t, Result[int]{Value: 1, Retries: 1}, results[0])
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+		require.Equal(t, 10*time.Millisecond, policy.backoff(0))
+		require.Equal(t, 20*time.Millisecond, policy.backoff(1))
+		require.Equal(t, 40*time.Millisecond, policy.backoff(2))
+		require.Equal(t, 50*time.Millisecond, policy.backoff(3))
+
+		policy.Jitter = 0.5
+		for i := 0; i < 100; i++ {
+			delay := policy.backoff(1)
+			require.GreaterOrEqual(t, delay, 10*time.Millisecond)
+			require.LessOrEqual(t, delay, 30*time.Millisecond)
+		}
+	})
+}
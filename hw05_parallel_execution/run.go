@@ -1,9 +1,11 @@
 package hw05parallelexecution
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var (
@@ -15,47 +17,207 @@ var (
 type Task func() error
 
 // Run starts tasks in n goroutines and stops its work when receiving m errors from tasks.
-func Run(tasks []Task, n, m int) error {
+// WithStopPolicy replaces the errors limit m with another stop condition.
+func Run(tasks []Task, n, m int, opts ...Option) error {
 	if len(tasks) == 0 {
 		return ErrEmptyTaskList
 	}
+	o := newOptions(opts)
+	if err := validate(n, m, o); err != nil {
+		return err
+	}
+	return execute(context.Background(), sliceSource(tasks), len(tasks), n, m, o)
+}
+
+// RunStream works like Run but receives tasks from the channel until it is closed or ctx is done.
+// The channel is not drained after the errors limit is exceeded, so producers should not block forever on it.
+func RunStream(ctx context.Context, tasks <-chan Task, n, m int, opts ...Option) error {
+	if tasks == nil {
+		return ErrEmptyTaskList
+	}
+	o := newOptions(opts)
+	if err := validate(n, m, o); err != nil {
+		return err
+	}
+	return execute(ctx, tasks, n, n, m, o)
+}
+
+func validate(n, m int, opts *options) error {
 	if n <= 0 {
 		return ErrWrongNumberOfGoroutines
 	}
-	if m <= 0 {
+	if m <= 0 && opts.stop == nil {
 		return ErrErrorsLimitExceeded
 	}
+	return nil
+}
+
+func sliceSource(tasks []Task) <-chan Task {
+	tasksCh := make(chan Task, len(tasks))
+	for _, t := range tasks {
+		tasksCh <- t
+	}
+	close(tasksCh)
+	return tasksCh
+}
+
+type executor struct {
+	opts       *options
+	dispatcher *dispatcher
+	stop       StopPolicy
+	stopped    int32
+	cancel     context.CancelFunc
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// execute dispatches tasks to n workers until the tasks run out, the stop policy fires or ctx is done.
+// Without a stop policy in opts the work stops after receiving m errors.
+// Up to lookahead received tasks are kept pending to be reordered by priorities and group limits.
+func execute(ctx context.Context, tasks <-chan Task, lookahead, n, m int, opts *options) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	return executeWithCancel(runCtx, cancel, tasks, lookahead, n, m, opts)
+}
+
+// executeWithCancel works like execute in the run context runCtx, which cancel must cancel.
+// The stop policy calls cancel, so tasks watching runCtx see the stop.
+func executeWithCancel(
+	runCtx context.Context,
+	cancel context.CancelFunc,
+	tasks <-chan Task,
+	lookahead, n, m int,
+	opts *options,
+) error {
+	e := &executor{
+		opts:       opts,
+		dispatcher: newDispatcher(tasks, lookahead, opts),
+		stop:       opts.stop,
+		cancel:     cancel,
+	}
+	if e.stop == nil {
+		e.stop = MaxErrors(m)
+	}
+	if opts.progress != nil {
+		opts.progress.reset(tasks)
+	}
 
 	wg := &sync.WaitGroup{}
 	wg.Add(n)
 
-	var errCount int32
-	tasksCh := make(chan Task)
+	jobsCh := make(chan job)
 
 	for i := 0; i < n; i++ {
-		go worker(wg, tasksCh, &errCount)
+		go e.worker(runCtx, wg, jobsCh, nil)
 	}
 
-	for _, t := range tasks {
-		if atomic.LoadInt32(&errCount) >= int32(m) {
+loop:
+	for !e.limitExceeded() && runCtx.Err() == nil {
+		j, ok := e.dispatcher.next(runCtx)
+		if !ok {
 			break
 		}
-		tasksCh <- t
+		select {
+		case <-runCtx.Done():
+			break loop
+		case jobsCh <- j:
+		}
 	}
-	close(tasksCh)
+	close(jobsCh)
 
 	wg.Wait()
-	if atomic.LoadInt32(&errCount) >= int32(m) {
+	if e.limitExceeded() {
 		return ErrErrorsLimitExceeded
 	}
-	return nil
+	return runCtx.Err()
+}
+
+func (e *executor) limitExceeded() bool {
+	return atomic.LoadInt32(&e.stopped) == 1
+}
+
+// finish accounts the result of a task and stops the work when the stop policy fires.
+func (e *executor) finish(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.stats.Done++
+	if err != nil {
+		e.stats.Failed++
+		e.stats.ConsecutiveFailures++
+	} else {
+		e.stats.ConsecutiveFailures = 0
+	}
+
+	if e.stop(e.stats) {
+		atomic.StoreInt32(&e.stopped, 1)
+		e.cancel()
+	}
 }
 
-func worker(group *sync.WaitGroup, tasksCh chan Task, errCount *int32) {
+// worker runs jobs until jobsCh is closed or quit is closed.
+func (e *executor) worker(ctx context.Context, group *sync.WaitGroup, jobsCh <-chan job, quit <-chan struct{}) {
 	defer group.Done()
-	for task := range tasksCh {
-		if task() != nil {
-			atomic.AddInt32(errCount, 1)
+	for {
+		select {
+		case <-quit:
+			return
+		case j, ok := <-jobsCh:
+			if !ok {
+				return
+			}
+			e.process(ctx, j)
 		}
 	}
 }
+
+func (e *executor) process(ctx context.Context, j job) {
+	e.started(j)
+	start := time.Now()
+	retries, err := e.runTask(ctx, j)
+	e.dispatcher.release(j)
+	e.finish(err)
+	e.finished(TaskEvent{Task: j.index, Retries: retries, Duration: time.Since(start), Err: err})
+	if j.done != nil {
+		j.done(err)
+	}
+}
+
+func (e *executor) runTask(ctx context.Context, j job) (int, error) {
+	task := func() error {
+		return safeCall(j.task)
+	}
+	if e.opts.retry == nil {
+		return 0, task()
+	}
+
+	retries, err := e.opts.retry.do(ctx, task)
+	if e.opts.retry.Report != nil {
+		e.opts.retry.Report(j.index, retries, err)
+	}
+	return retries, err
+}
+
+func (e *executor) started(j job) {
+	if e.opts.progress != nil {
+		e.opts.progress.taskStarted()
+	}
+	if e.opts.observer != nil {
+		e.opts.observer.TaskStarted(TaskEvent{Task: j.index})
+	}
+}
+
+func (e *executor) finished(event TaskEvent) {
+	if e.opts.progress != nil {
+		e.opts.progress.taskFinished(event.Err)
+	}
+	if e.opts.observer == nil {
+		return
+	}
+	if event.Err != nil {
+		e.opts.observer.TaskFailed(event)
+	} else {
+		e.opts.observer.TaskFinished(event)
+	}
+}
@@ -0,0 +1,43 @@
+package hw05parallelexecution
+
+// Stats counts the tasks finished so far.
+type Stats struct {
+	Done                int
+	Failed              int
+	ConsecutiveFailures int
+}
+
+// StopPolicy reports whether the work should be stopped; it is checked after every finished task.
+type StopPolicy func(stats Stats) bool
+
+// MaxErrors stops the work after receiving m errors, it is the policy used by Run by default.
+func MaxErrors(m int) StopPolicy {
+	return func(stats Stats) bool {
+		return stats.Failed >= m
+	}
+}
+
+// IgnoreErrors never stops the work, so all the tasks are run.
+func IgnoreErrors() StopPolicy {
+	return func(Stats) bool {
+		return false
+	}
+}
+
+// MaxErrorRate stops the work when more than percent of finished tasks failed,
+// but not before minSample tasks are finished.
+func MaxErrorRate(percent float64, minSample int) StopPolicy {
+	return func(stats Stats) bool {
+		if stats.Done == 0 || stats.Done < minSample {
+			return false
+		}
+		return float64(stats.Failed)*100/float64(stats.Done) > percent
+	}
+}
+
+// MaxConsecutiveFailures stops the work after n failed tasks in a row.
+func MaxConsecutiveFailures(n int) StopPolicy {
+	return func(stats Stats) bool {
+		return stats.ConsecutiveFailures >= n
+	}
+}
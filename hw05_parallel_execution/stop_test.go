@@ -0,0 +1,81 @@
+package hw05parallelexecution
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestStopPolicy(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	makeTasks := func(count int, failed func(i int) bool, runTasksCount *int32) []Task {
+		tasks := make([]Task, 0, count)
+		for i := 0; i < count; i++ {
+			i := i
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(runTasksCount, 1)
+				if failed(i) {
+					return errors.New("failed")
+				}
+				return nil
+			})
+		}
+		return tasks
+	}
+
+	t.Run("ignore errors", func(t *testing.T) {
+		var runTasksCount int32
+		tasks := makeTasks(50, func(int) bool { return true }, &runTasksCount)
+
+		err := Run(tasks, 5, 0, WithStopPolicy(IgnoreErrors()))
+		require.NoError(t, err)
+		require.Equal(t, int32(50), runTasksCount)
+	})
+
+	t.Run("error rate", func(t *testing.T) {
+		var runTasksCount int32
+		tasks := makeTasks(100, func(i int) bool { return i%2 == 0 }, &runTasksCount)
+
+		err := Run(tasks, 1, 0, WithStopPolicy(MaxErrorRate(60, 10)))
+		require.NoError(t, err)
+		require.Equal(t, int32(100), runTasksCount)
+
+		runTasksCount = 0
+		err = Run(tasks, 1, 0, WithStopPolicy(MaxErrorRate(40, 10)))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.InDelta(t, 10, runTasksCount, 1, "extra tasks were started")
+	})
+
+	t.Run("consecutive failures", func(t *testing.T) {
+		var runTasksCount int32
+		tasks := makeTasks(100, func(i int) bool { return i%3 != 0 || i > 50 }, &runTasksCount)
+
+		err := Run(tasks, 1, 0, WithStopPolicy(MaxConsecutiveFailures(3)))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.InDelta(t, 52, runTasksCount, 1, "extra tasks were started")
+	})
+
+	t.Run("custom predicate", func(t *testing.T) {
+		var runTasksCount int32
+		tasks := makeTasks(100, func(int) bool { return false }, &runTasksCount)
+
+		err := Run(tasks, 1, 0, WithStopPolicy(func(stats Stats) bool {
+			return stats.Done >= 7
+		}))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.InDelta(t, 7, runTasksCount, 1, "extra tasks were started")
+	})
+
+	t.Run("policies", func(t *testing.T) {
+		require.True(t, MaxErrors(2)(Stats{Done: 5, Failed: 2}))
+		require.False(t, MaxErrors(3)(Stats{Done: 5, Failed: 2}))
+		require.False(t, IgnoreErrors()(Stats{Done: 5, Failed: 5}))
+		require.False(t, MaxErrorRate(10, 5)(Stats{Done: 4, Failed: 4}))
+		require.True(t, MaxErrorRate(10, 5)(Stats{Done: 5, Failed: 1}))
+		require.True(t, MaxConsecutiveFailures(2)(Stats{ConsecutiveFailures: 2}))
+	})
+}
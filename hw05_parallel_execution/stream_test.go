@@ -0,0 +1,82 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestRunStream(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	produce := func(ctx context.Context, count int, task Task) <-chan Task {
+		tasksCh := make(chan Task)
+		go func() {
+			defer close(tasksCh)
+			for i := 0; i < count; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case tasksCh <- task:
+				}
+			}
+		}()
+		return tasksCh
+	}
+
+	t.Run("nil stream", func(t *testing.T) {
+		err := RunStream(context.Background(), nil, 1, 1)
+		require.ErrorIs(t, err, ErrEmptyTaskList)
+	})
+
+	t.Run("wrong number of goroutines", func(t *testing.T) {
+		err := RunStream(context.Background(), make(chan Task), 0, 1)
+		require.ErrorIs(t, err, ErrWrongNumberOfGoroutines)
+	})
+
+	t.Run("all tasks from stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var runTasksCount int32
+		tasksCount := 1000
+		err := RunStream(ctx, produce(ctx, tasksCount, func() error {
+			atomic.AddInt32(&runTasksCount, 1)
+			return nil
+		}), 10, 1)
+		require.NoError(t, err)
+		require.Equal(t, int32(tasksCount), runTasksCount)
+	})
+
+	t.Run("errors limit exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var runTasksCount int32
+		workersCount := 5
+		maxErrorsCount := 10
+		err := RunStream(ctx, produce(ctx, 1000, func() error {
+			atomic.AddInt32(&runTasksCount, 1)
+			time.Sleep(time.Millisecond)
+			return errors.New("failed")
+		}), workersCount, maxErrorsCount)
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+		require.LessOrEqual(t, runTasksCount, int32(workersCount+maxErrorsCount), "extra tasks were started")
+	})
+
+	t.Run("context canceled while waiting for tasks", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(10 * time.Millisecond)
+			cancel()
+		}()
+
+		err := RunStream(ctx, make(chan Task), 2, 1)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,217 @@
+package hw06pipelineexecution
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrCorruptedCheckpoint = errors.New("corrupted checkpoint file")
+
+// Record is a value of the source with its offset, checkpointed pipelines pass records between the stages.
+type Record struct {
+	Offset int64
+	Value  interface{}
+}
+
+const (
+	defaultCommitEvery    = 1000
+	defaultCommitInterval = time.Second
+)
+
+// Checkpoint is a file keeping the number of leading source records processed by the pipeline.
+// Acknowledged records are committed to the file in batches, see WithCommitEvery and WithCommitInterval.
+type Checkpoint struct {
+	every    int64
+	interval time.Duration
+
+	mu        sync.Mutex
+	file      *os.File
+	committed int64
+	written   int64
+	acked     map[int64]struct{}
+	err       error
+
+	quit, done chan struct{}
+}
+
+// CheckpointOption configures OpenCheckpoint.
+type CheckpointOption func(*Checkpoint)
+
+// WithCommitEvery writes the checkpoint file once the offset moves by records, 1 writes it on every move.
+func WithCommitEvery(records int) CheckpointOption {
+	return func(c *Checkpoint) {
+		c.every = int64(records)
+	}
+}
+
+// WithCommitInterval writes the moved offset to the checkpoint file every d, 0 disables periodic commits.
+func WithCommitInterval(d time.Duration) CheckpointOption {
+	return func(c *Checkpoint) {
+		c.interval = d
+	}
+}
+
+// OpenCheckpoint opens the checkpoint file creating it if it does not exist.
+// By default the offset is committed every 1000 records or every second, whichever comes first.
+func OpenCheckpoint(path string, opts ...CheckpointOption) (*Checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{
+		every:    defaultCommitEvery,
+		interval: defaultCommitInterval,
+		file:     file,
+		acked:    make(map[int64]struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	buf := make([]byte, 8)
+	n, err := file.ReadAt(buf, 0)
+	switch {
+	case n == 0 && errors.Is(err, io.EOF):
+	case err != nil && !errors.Is(err, io.EOF), n != len(buf):
+		_ = file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrCorruptedCheckpoint, path)
+	default:
+		c.committed = int64(binary.BigEndian.Uint64(buf))
+		c.written = c.committed
+	}
+
+	if c.interval > 0 {
+		c.quit, c.done = make(chan struct{}), make(chan struct{})
+		go c.commitPeriodically()
+	}
+	return c, nil
+}
+
+// Offset returns the offset of the first source record that is not acknowledged yet.
+// It may be ahead of the offset saved in the file until the next commit.
+func (c *Checkpoint) Offset() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed
+}
+
+// Ack marks the record as processed. The consumer of the pipeline must ack every record once it is handled,
+// and stages dropping records must ack them too, otherwise the checkpoint does not move past them.
+func (c *Checkpoint) Ack(offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if offset < c.committed {
+		return
+	}
+	c.acked[offset] = struct{}{}
+
+	for {
+		if _, ok := c.acked[c.committed]; !ok {
+			break
+		}
+		delete(c.acked, c.committed)
+		c.committed++
+	}
+	if c.committed-c.written >= c.every {
+		c.write()
+	}
+}
+
+// Commit writes the current offset to the checkpoint file and returns the first error of writing it.
+func (c *Checkpoint) Commit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.write()
+	return c.err
+}
+
+// Err returns the first error of writing the checkpoint file.
+func (c *Checkpoint) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close commits the current offset and closes the file.
+func (c *Checkpoint) Close() error {
+	if c.quit != nil {
+		close(c.quit)
+		<-c.done
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.write()
+	if err := c.file.Close(); err != nil && c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+func (c *Checkpoint) commitPeriodically() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+			_ = c.Commit()
+		}
+	}
+}
+
+// write saves the offset if it moved since the last write, keeping the first error.
+func (c *Checkpoint) write() {
+	if c.committed == c.written || c.err != nil {
+		return
+	}
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(c.committed))
+	_, err := c.file.WriteAt(buf, 0)
+	if err == nil {
+		err = c.file.Sync()
+	}
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.written = c.committed
+}
+
+// ExecutePipelineWithCheckpoint runs the pipeline over the records of the source starting from the offset
+// saved in the checkpoint. The stages receive and send Record values, a record is processed once
+// the consumer of the output acks it in the checkpoint after handling it. After a restart the records
+// that were not committed are passed to the pipeline again, so every record is processed at least once.
+func ExecutePipelineWithCheckpoint(source func(offset int64) In, done In, cp *Checkpoint, stages ...Stage) Out {
+	if len(stages) == 0 {
+		return nil
+	}
+
+	offset := cp.Offset()
+	records := make(Bi)
+	go func() {
+		defer close(records)
+		in := source(offset)
+		for i := offset; ; i++ {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			if !send(done, records, Record{Offset: i, Value: v}) {
+				return
+			}
+		}
+	}()
+
+	return ExecutePipeline(records, done, stages...)
+}
@@ -0,0 +1,129 @@
+package hw06pipelineexecution
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	source := func(offset int64) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data[offset:] {
+				in <- v
+			}
+		}()
+		return in
+	}
+	double := func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				r := v.(Record)
+				r.Value = r.Value.(int) * 2
+				out <- r
+			}
+		}()
+		return out
+	}
+
+	t.Run("ack", func(t *testing.T) {
+		cp, err := OpenCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+		require.NoError(t, err)
+		defer cp.Close()
+
+		cp.Ack(1)
+		require.Equal(t, int64(0), cp.Offset())
+		cp.Ack(0)
+		require.Equal(t, int64(2), cp.Offset())
+		cp.Ack(0)
+		cp.Ack(3)
+		cp.Ack(2)
+		require.Equal(t, int64(4), cp.Offset())
+		require.NoError(t, cp.Err())
+	})
+
+	t.Run("corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		require.NoError(t, os.WriteFile(path, []byte{1, 2, 3}, 0o600))
+
+		_, err := OpenCheckpoint(path)
+		require.ErrorIs(t, err, ErrCorruptedCheckpoint)
+	})
+
+	t.Run("batched commits", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		saved := func() int64 {
+			buf, err := os.ReadFile(path)
+			require.NoError(t, err)
+			if len(buf) == 0 {
+				return 0
+			}
+			return int64(binary.BigEndian.Uint64(buf))
+		}
+
+		cp, err := OpenCheckpoint(path, WithCommitEvery(3), WithCommitInterval(0))
+		require.NoError(t, err)
+		cp.Ack(0)
+		cp.Ack(1)
+		require.Equal(t, int64(2), cp.Offset())
+		require.Equal(t, int64(0), saved())
+		cp.Ack(2)
+		require.Equal(t, int64(3), saved())
+		cp.Ack(3)
+		require.Equal(t, int64(3), saved())
+		require.NoError(t, cp.Close())
+		require.Equal(t, int64(4), saved())
+
+		cp, err = OpenCheckpoint(path, WithCommitEvery(1000), WithCommitInterval(time.Millisecond))
+		require.NoError(t, err)
+		defer cp.Close()
+		cp.Ack(4)
+		require.Eventually(t, func() bool {
+			return saved() == 5
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("resume after restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint")
+
+		// The first run dies while handling the fourth record.
+		cp, err := OpenCheckpoint(path)
+		require.NoError(t, err)
+		done := make(Bi)
+		out := ExecutePipelineWithCheckpoint(source, done, cp, double)
+		for i := 0; i < 4; i++ {
+			r := (<-out).(Record)
+			if i < 3 {
+				cp.Ack(r.Offset)
+			}
+		}
+		close(done)
+		require.Equal(t, int64(3), cp.Offset())
+		require.NoError(t, cp.Close())
+
+		cp, err = OpenCheckpoint(path)
+		require.NoError(t, err)
+		defer cp.Close()
+		require.Equal(t, int64(3), cp.Offset())
+
+		result := make([]Record, 0, len(data))
+		for v := range ExecutePipelineWithCheckpoint(source, nil, cp, double) {
+			r := v.(Record)
+			result = append(result, r)
+			cp.Ack(r.Offset)
+		}
+		require.Len(t, result, 7)
+		require.Equal(t, Record{Offset: 3, Value: 8}, result[0])
+		require.Equal(t, Record{Offset: 9, Value: 20}, result[6])
+		require.Equal(t, int64(len(data)), cp.Offset())
+	})
+}
@@ -0,0 +1,129 @@
+package hw06pipelineexecution
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// FallibleStage is a stage that reports its failures with fail.
+type FallibleStage func(in In, fail func(err error)) (out Out)
+
+// ErrorMode defines how ExecutePipelineWithErrors handles failures of stages.
+type ErrorMode int
+
+const (
+	// FirstError stops the pipeline on the first failure and reports only it.
+	FirstError ErrorMode = iota
+	// AllErrors keeps the pipeline running and reports every failure.
+	AllErrors
+)
+
+// Errors holds all the failures of the pipeline in AllErrors mode.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is lets errors.Is look through the failures.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As lets errors.As look through the failures, the first matching one is set to target.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Infallible adapts the stage that never fails to be used in ExecutePipelineWithErrors.
+func Infallible(stage Stage) FallibleStage {
+	return func(in In, _ func(error)) Out {
+		return stage(in)
+	}
+}
+
+// ExecutePipelineWithErrors works like ExecutePipeline but lets stages fail.
+// In FirstError mode the first failure stops passing values to all the stages as closing done does.
+// The returned wait function must be called after out is drained, it returns the failures of the stages.
+func ExecutePipelineWithErrors(in In, done In, mode ErrorMode, stages ...FallibleStage) (out Out, wait func() error) {
+	if len(stages) == 0 {
+		return nil, func() error { return nil }
+	}
+
+	c := &errorCollector{mode: mode, stop: make(chan struct{})}
+	last := in
+	for i := range stages {
+		stage := stages[i]
+		last = execStage(func(in In) Out {
+			return stage(in, c.fail)
+		}, last, done, c.stop, stageConfig{drain: i > 0})
+	}
+
+	finished := make(chan struct{})
+	result := make(Bi)
+	go func() {
+		defer close(finished)
+		defer close(result)
+		for v := range last {
+			result <- v
+		}
+	}()
+
+	return result, func() error {
+		<-finished
+		return c.err()
+	}
+}
+
+type errorCollector struct {
+	mode ErrorMode
+	stop chan struct{}
+
+	mu   sync.Mutex
+	errs Errors
+}
+
+func (c *errorCollector) fail(err error) {
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode == FirstError {
+		if len(c.errs) == 0 {
+			c.errs = append(c.errs, err)
+			close(c.stop)
+		}
+		return
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *errorCollector) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case len(c.errs) == 0:
+		return nil
+	case c.mode == FirstError:
+		return c.errs[0]
+	default:
+		return append(Errors(nil), c.errs...)
+	}
+}
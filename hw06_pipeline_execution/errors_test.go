@@ -0,0 +1,122 @@
+package hw06pipelineexecution
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineWithErrors(t *testing.T) {
+	// Stage generator failing on the values matching the predicate
+	g := func(failed func(v int) bool) FallibleStage {
+		return func(in In, fail func(error)) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					time.Sleep(sleepPerStage / 10)
+					if failed(v.(int)) {
+						fail(fmt.Errorf("bad value %d", v))
+						continue
+					}
+					out <- v
+				}
+			}()
+			return out
+		}
+	}
+
+	produce := func(data []int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				in <- v
+			}
+		}()
+		return in
+	}
+
+	t.Run("empty stages", func(t *testing.T) {
+		out, wait := ExecutePipelineWithErrors(make(Bi), nil, FirstError)
+		require.Nil(t, out)
+		require.NoError(t, wait())
+	})
+
+	t.Run("no errors", func(t *testing.T) {
+		out, wait := ExecutePipelineWithErrors(produce([]int{1, 2, 3}), nil, FirstError,
+			g(func(int) bool { return false }),
+			Infallible(func(in In) Out { return in }),
+		)
+
+		result := make([]interface{}, 0, 3)
+		for v := range out {
+			result = append(result, v)
+		}
+		require.NoError(t, wait())
+		require.Equal(t, []interface{}{1, 2, 3}, result)
+	})
+
+	t.Run("first error stops the pipeline", func(t *testing.T) {
+		data := make([]int, 100)
+		for i := range data {
+			data[i] = i
+		}
+
+		out, wait := ExecutePipelineWithErrors(produce(data), nil, FirstError,
+			g(func(v int) bool { return v == 3 }),
+			g(func(int) bool { return false }),
+		)
+
+		result := make([]interface{}, 0, len(data))
+		for v := range out {
+			result = append(result, v)
+		}
+		require.EqualError(t, wait(), "bad value 3")
+		require.Less(t, len(result), len(data)-1)
+	})
+
+	t.Run("all errors", func(t *testing.T) {
+		out, wait := ExecutePipelineWithErrors(produce([]int{1, 2, 3, 4, 5, 6}), nil, AllErrors,
+			g(func(v int) bool { return v%2 == 0 }),
+			g(func(v int) bool { return v == 5 }),
+		)
+
+		result := make([]interface{}, 0, 6)
+		for v := range out {
+			result = append(result, v)
+		}
+		err := wait()
+		require.Equal(t, []interface{}{1, 3}, result)
+
+		var errs Errors
+		require.True(t, errors.As(err, &errs))
+		require.Len(t, errs, 4)
+		require.ElementsMatch(t, []string{"bad value 2", "bad value 4", "bad value 5", "bad value 6"},
+			[]string{errs[0].Error(), errs[1].Error(), errs[2].Error(), errs[3].Error()})
+	})
+}
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.code)
+}
+
+func TestErrors(t *testing.T) {
+	errBad := errors.New("bad")
+	err := fmt.Errorf("pipeline: %w", Errors{errors.New("first"), fmt.Errorf("second: %w", errBad), &codeError{code: 7}})
+
+	require.ErrorIs(t, err, errBad)
+	require.NotErrorIs(t, err, errors.New("bad"))
+
+	var codeErr *codeError
+	require.ErrorAs(t, err, &codeErr)
+	require.Equal(t, 7, codeErr.code)
+	require.EqualError(t, err, "pipeline: first; second: bad; code 7")
+}
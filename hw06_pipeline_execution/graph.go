@@ -0,0 +1,294 @@
+package hw06pipelineexecution
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrNoGraphInput       = errors.New("graph has no input")
+	ErrMultipleInputs     = errors.New("graph has more than one input")
+	ErrDuplicateNode      = errors.New("duplicate node")
+	ErrUnknownNode        = errors.New("unknown node")
+	ErrNodeWithoutSources = errors.New("node has no sources")
+	ErrGraphCycle         = errors.New("graph has a cycle")
+)
+
+// Branch is an output of Split receiving the values Match returns true for.
+type Branch struct {
+	Name  string
+	Match func(v interface{}) bool
+}
+
+// Graph is a pipeline of named nodes forming a directed acyclic graph.
+// A node reading several sources merges them, a node read by several nodes broadcasts its values to all of them.
+// The nodes nobody reads are the outputs of the graph.
+type Graph struct {
+	nodes []*graphNode
+	names map[string]*graphNode
+	err   error
+}
+
+type graphNode struct {
+	name    string
+	sources []string
+	input   bool
+	stage   Stage
+	split   *split
+	branch  int
+}
+
+type split struct {
+	branches []Branch
+}
+
+func NewGraph() *Graph {
+	return &Graph{names: make(map[string]*graphNode)}
+}
+
+// Input adds the node the values passed to Execute come from.
+func (g *Graph) Input(name string) *Graph {
+	return g.add(&graphNode{name: name, input: true})
+}
+
+// Stage adds the node running the stage over the values of the sources.
+func (g *Graph) Stage(name string, stage Stage, sources ...string) *Graph {
+	return g.add(&graphNode{name: name, sources: sources, stage: stage})
+}
+
+// Merge adds the node passing the values of all the sources.
+func (g *Graph) Merge(name string, sources ...string) *Graph {
+	return g.add(&graphNode{name: name, sources: sources})
+}
+
+// Split adds a node for every branch, a value of the source goes to the first branch matching it
+// and is dropped if there is no such branch.
+func (g *Graph) Split(source string, branches ...Branch) *Graph {
+	s := &split{branches: branches}
+	for i, b := range branches {
+		g.add(&graphNode{name: b.Name, sources: []string{source}, split: s, branch: i})
+	}
+	return g
+}
+
+func (g *Graph) add(n *graphNode) *Graph {
+	if _, ok := g.names[n.name]; ok && g.err == nil {
+		g.err = fmt.Errorf("%w: %s", ErrDuplicateNode, n.name)
+	}
+	g.names[n.name] = n
+	g.nodes = append(g.nodes, n)
+	return g
+}
+
+// Validate checks that the graph has a single input, all the sources exist and there are no cycles.
+func (g *Graph) Validate() error {
+	_, err := g.sort()
+	return err
+}
+
+// sort returns the nodes in topological order.
+func (g *Graph) sort() ([]*graphNode, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var inputs int
+	pending := make(map[*graphNode]int, len(g.nodes))
+	readers := make(map[string][]*graphNode, len(g.nodes))
+	for _, n := range g.nodes {
+		if n.input {
+			inputs++
+			continue
+		}
+		if len(n.sources) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNodeWithoutSources, n.name)
+		}
+		for _, source := range n.sources {
+			if _, ok := g.names[source]; !ok {
+				return nil, fmt.Errorf("%w: %s reads %s", ErrUnknownNode, n.name, source)
+			}
+			readers[source] = append(readers[source], n)
+		}
+		pending[n] = len(n.sources)
+	}
+	switch {
+	case inputs == 0:
+		return nil, ErrNoGraphInput
+	case inputs > 1:
+		return nil, ErrMultipleInputs
+	}
+
+	sorted := make([]*graphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		if n.input {
+			sorted = append(sorted, n)
+		}
+	}
+	for i := 0; i < len(sorted); i++ {
+		for _, r := range readers[sorted[i].name] {
+			pending[r]--
+			if pending[r] == 0 {
+				sorted = append(sorted, r)
+			}
+		}
+	}
+	if len(sorted) != len(g.nodes) {
+		return nil, ErrGraphCycle
+	}
+	return sorted, nil
+}
+
+// Execute starts the graph over the values of in and returns its outputs by node names.
+// Closing done stops it like ExecutePipeline does.
+func (g *Graph) Execute(in In, done In) (map[string]Out, error) {
+	sorted, err := g.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	// Every split reads its source once for all its branches.
+	readers := make(map[string]int, len(sorted))
+	counted := make(map[*split]bool)
+	for _, n := range sorted {
+		if n.split != nil {
+			if counted[n.split] {
+				continue
+			}
+			counted[n.split] = true
+		}
+		for _, source := range n.sources {
+			readers[source]++
+		}
+	}
+
+	outs := make(map[string][]Out, len(sorted))
+	take := func(name string) Out {
+		o := outs[name][0]
+		outs[name] = outs[name][1:]
+		return o
+	}
+	splits := make(map[*split][]Out)
+	results := make(map[string]Out)
+
+	for _, n := range sorted {
+		var out Out
+		switch {
+		case n.input:
+			// Stopped stages do not drain the input, so its owner is not blocked by the graph.
+			out = execStage(func(in In) Out { return in }, in, done, nil, stageConfig{})
+		case n.split != nil:
+			if _, ok := splits[n.split]; !ok {
+				splits[n.split] = splitOut(done, take(n.sources[0]), n.split.branches)
+			}
+			out = splits[n.split][n.branch]
+		default:
+			sources := make([]Out, 0, len(n.sources))
+			for _, source := range n.sources {
+				sources = append(sources, take(source))
+			}
+			out = mergeOut(done, sources)
+			if n.stage != nil {
+				out = execStage(n.stage, out, done, nil, stageConfig{drain: true})
+			}
+		}
+
+		if readers[n.name] == 0 {
+			results[n.name] = out
+			continue
+		}
+		outs[n.name] = broadcastOut(done, out, readers[n.name])
+	}
+	return results, nil
+}
+
+func mergeOut(done In, sources []Out) Out {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+
+	out := make(Bi)
+	wg := &sync.WaitGroup{}
+	wg.Add(len(sources))
+	for _, source := range sources {
+		go func(source Out) {
+			defer wg.Done()
+			if forward(source, out, done, nil, nil) {
+				for range source { //nolint:revive
+				}
+			}
+		}(source)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func broadcastOut(done In, in Out, count int) []Out {
+	if count == 1 {
+		return []Out{in}
+	}
+
+	bis := make([]Bi, count)
+	outs := make([]Out, count)
+	for i := range bis {
+		bis[i] = make(Bi)
+		outs[i] = bis[i]
+	}
+	go func() {
+		defer func() {
+			for _, bi := range bis {
+				close(bi)
+			}
+			for range in { //nolint:revive
+			}
+		}()
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			for _, bi := range bis {
+				if !send(done, bi, v) {
+					return
+				}
+			}
+		}
+	}()
+	return outs
+}
+
+func splitOut(done In, in Out, branches []Branch) []Out {
+	bis := make([]Bi, len(branches))
+	outs := make([]Out, len(branches))
+	for i := range bis {
+		bis[i] = make(Bi)
+		outs[i] = bis[i]
+	}
+	go func() {
+		defer func() {
+			for _, bi := range bis {
+				close(bi)
+			}
+			for range in { //nolint:revive
+			}
+		}()
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			for i, b := range branches {
+				if b.Match(v) {
+					if !send(done, bis[i], v) {
+						return
+					}
+					break
+				}
+			}
+		}
+	}()
+	return outs
+}
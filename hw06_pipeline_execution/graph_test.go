@@ -0,0 +1,126 @@
+package hw06pipelineexecution
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	double := func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				out <- v.(int) * 2
+			}
+		}()
+		return out
+	}
+	negate := func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				out <- -v.(int)
+			}
+		}()
+		return out
+	}
+	even := func(v interface{}) bool { return v.(int)%2 == 0 }
+	odd := func(v interface{}) bool { return v.(int)%2 != 0 }
+
+	produce := func(data ...int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				in <- v
+			}
+		}()
+		return in
+	}
+
+	// collectAll reads all the outputs concurrently, as broadcasting waits for every reader.
+	collectAll := func(outs map[string]Out) map[string][]interface{} {
+		mu := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		result := make(map[string][]interface{}, len(outs))
+		for name, out := range outs {
+			wg.Add(1)
+			go func(name string, out Out) {
+				defer wg.Done()
+				values := make([]interface{}, 0)
+				for v := range out {
+					values = append(values, v)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				result[name] = values
+			}(name, out)
+		}
+		wg.Wait()
+		return result
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		require.ErrorIs(t, NewGraph().Stage("a", double, "b").Input("b").Input("c").Validate(), ErrMultipleInputs)
+		require.ErrorIs(t, NewGraph().Stage("a", double, "b").Validate(), ErrUnknownNode)
+		require.ErrorIs(t, NewGraph().Input("in").Merge("a").Validate(), ErrNodeWithoutSources)
+		require.ErrorIs(t, NewGraph().Input("in").Stage("a", double, "in").Merge("a", "in").Validate(), ErrDuplicateNode)
+		require.ErrorIs(t, NewGraph().Validate(), ErrNoGraphInput)
+
+		cyclic := NewGraph().
+			Input("in").
+			Merge("a", "in", "c").
+			Stage("b", double, "a").
+			Stage("c", negate, "b")
+		require.ErrorIs(t, cyclic.Validate(), ErrGraphCycle)
+
+		_, err := cyclic.Execute(make(Bi), nil)
+		require.ErrorIs(t, err, ErrGraphCycle)
+	})
+
+	t.Run("linear", func(t *testing.T) {
+		outs, err := NewGraph().
+			Input("in").
+			Stage("double", double, "in").
+			Stage("negate", negate, "double").
+			Execute(produce(1, 2, 3), nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string][]interface{}{"negate": {-2, -4, -6}}, collectAll(outs))
+	})
+
+	t.Run("split, broadcast and merge", func(t *testing.T) {
+		outs, err := NewGraph().
+			Input("in").
+			Split("in", Branch{Name: "even", Match: even}, Branch{Name: "odd", Match: odd}).
+			Stage("doubled", double, "even").
+			Stage("negated", negate, "even").
+			Merge("all", "doubled", "odd").
+			Execute(produce(1, 2, 3, 4, 5), nil)
+		require.NoError(t, err)
+
+		result := collectAll(outs)
+		require.Len(t, result, 2)
+		require.Equal(t, []interface{}{-2, -4}, result["negated"])
+		require.ElementsMatch(t, []interface{}{4, 8, 1, 3, 5}, result["all"])
+	})
+
+	t.Run("done", func(t *testing.T) {
+		done := make(Bi)
+		in := make(Bi)
+		outs, err := NewGraph().
+			Input("in").
+			Stage("a", double, "in").
+			Stage("b", negate, "in").
+			Execute(in, done)
+		require.NoError(t, err)
+
+		in <- 1
+		close(done)
+		close(in)
+		collectAll(outs)
+	})
+}
@@ -0,0 +1,115 @@
+package hw06pipelineexecution
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StageStats describes the input of a stage.
+type StageStats struct {
+	// Items is the number of values passed to the stage.
+	Items int64
+	// QueueDepth is the number of values waiting in the input buffer of the stage.
+	QueueDepth int
+	// Throughput is the number of values passed to the stage per second.
+	Throughput float64
+	// BlockedOnReceive is the time spent waiting for values from the previous stage.
+	BlockedOnReceive time.Duration
+	// BlockedOnSend is the time spent waiting for the stage to take values,
+	// the stage with the greatest one is the bottleneck of the pipeline.
+	BlockedOnSend time.Duration
+}
+
+// Metrics collects the stats of every stage of the pipeline it is passed to with WithMetrics.
+// It must not be shared by simultaneous pipelines.
+type Metrics struct {
+	mu     sync.Mutex
+	stages []*stageMetrics
+}
+
+// Snapshot returns the current stats of the stages in their order.
+func (m *Metrics) Snapshot() []StageStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]StageStats, 0, len(m.stages))
+	for _, s := range m.stages {
+		stats = append(stats, s.stats())
+	}
+	return stats
+}
+
+func (m *Metrics) init(stages int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stages = make([]*stageMetrics, stages)
+	for i := range m.stages {
+		m.stages[i] = &stageMetrics{}
+	}
+}
+
+func (m *Metrics) stage(i int) *stageMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stages[i]
+}
+
+type stageMetrics struct {
+	items            int64
+	blockedOnReceive int64
+	blockedOnSend    int64
+
+	mu      sync.Mutex
+	queue   Bi
+	started time.Time
+}
+
+func (s *stageMetrics) start(queue Bi) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = queue
+	s.started = time.Now()
+}
+
+// now returns the start of a blocking wait, the time is not taken without metrics.
+func (s *stageMetrics) now() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (s *stageMetrics) received(waitStart time.Time) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.blockedOnReceive, int64(time.Since(waitStart)))
+}
+
+func (s *stageMetrics) sent(waitStart time.Time) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.blockedOnSend, int64(time.Since(waitStart)))
+	atomic.AddInt64(&s.items, 1)
+}
+
+func (s *stageMetrics) stats() StageStats {
+	s.mu.Lock()
+	queueDepth := len(s.queue)
+	elapsed := time.Since(s.started)
+	s.mu.Unlock()
+
+	stats := StageStats{
+		Items:            atomic.LoadInt64(&s.items),
+		QueueDepth:       queueDepth,
+		BlockedOnReceive: time.Duration(atomic.LoadInt64(&s.blockedOnReceive)),
+		BlockedOnSend:    time.Duration(atomic.LoadInt64(&s.blockedOnSend)),
+	}
+	if elapsed > 0 {
+		stats.Throughput = float64(stats.Items) / elapsed.Seconds()
+	}
+	return stats
+}
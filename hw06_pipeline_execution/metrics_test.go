@@ -0,0 +1,93 @@
+package hw06pipelineexecution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	// Stage generator
+	g := func(sleep time.Duration) Stage {
+		return func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					time.Sleep(sleep)
+					out <- v
+				}
+			}()
+			return out
+		}
+	}
+
+	produce := func(count int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for i := 0; i < count; i++ {
+				in <- i
+			}
+		}()
+		return in
+	}
+
+	t.Run("empty stages", func(t *testing.T) {
+		require.Nil(t, ExecutePipelineWithOptions(make(Bi), nil, nil))
+	})
+
+	t.Run("bottleneck", func(t *testing.T) {
+		metrics := &Metrics{}
+		stages := []Stage{g(0), g(sleepPerStage / 10), g(0)}
+
+		result := make([]interface{}, 0, 10)
+		for v := range ExecutePipelineWithOptions(produce(10), nil, stages, WithMetrics(metrics)) {
+			result = append(result, v)
+		}
+		require.Len(t, result, 10)
+
+		stats := metrics.Snapshot()
+		require.Len(t, stats, len(stages))
+		for _, s := range stats {
+			require.Equal(t, int64(10), s.Items)
+			require.Greater(t, s.Throughput, float64(0))
+		}
+		// The slow stage keeps the previous one waiting for it to take values.
+		require.Greater(t, stats[1].BlockedOnSend, stats[0].BlockedOnSend)
+		require.Greater(t, stats[1].BlockedOnSend, stats[2].BlockedOnSend)
+		require.Greater(t, stats[2].BlockedOnReceive, stats[1].BlockedOnReceive)
+	})
+
+	t.Run("buffers", func(t *testing.T) {
+		metrics := &Metrics{}
+		release := make(chan struct{})
+		blocked := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				<-release
+				for v := range in {
+					out <- v
+				}
+			}()
+			return out
+		}
+
+		out := ExecutePipelineWithOptions(produce(5), nil, []Stage{g(0), blocked},
+			WithBuffer(1), WithStageBuffer(1, 3), WithMetrics(metrics))
+
+		require.Eventually(t, func() bool {
+			return metrics.Snapshot()[1].QueueDepth == 3
+		}, time.Second, time.Millisecond)
+
+		close(release)
+		result := make([]interface{}, 0, 5)
+		for v := range out {
+			result = append(result, v)
+		}
+		require.Equal(t, []interface{}{0, 1, 2, 3, 4}, result)
+		require.Zero(t, metrics.Snapshot()[1].QueueDepth)
+	})
+}
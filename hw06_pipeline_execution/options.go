@@ -0,0 +1,49 @@
+package hw06pipelineexecution
+
+// Option configures ExecutePipelineWithOptions.
+type Option func(*options)
+
+type options struct {
+	buffer       int
+	stageBuffers map[int]int
+	metrics      *Metrics
+}
+
+// WithBuffer sets the buffer size of the input channel of every stage.
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.buffer = size
+	}
+}
+
+// WithStageBuffer sets the buffer size of the input channel of the stage with the given index,
+// overriding WithBuffer for it.
+func WithStageBuffer(stage, size int) Option {
+	return func(o *options) {
+		if o.stageBuffers == nil {
+			o.stageBuffers = make(map[int]int)
+		}
+		o.stageBuffers[stage] = size
+	}
+}
+
+// WithMetrics makes the pipeline collect its per stage metrics into m.
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+func (o *options) stageConfig(stage int) stageConfig {
+	cfg := stageConfig{drain: stage > 0, buffer: o.buffer}
+	if size, ok := o.stageBuffers[stage]; ok {
+		cfg.buffer = size
+	}
+	if cfg.buffer < 0 {
+		cfg.buffer = 0
+	}
+	if o.metrics != nil {
+		cfg.metrics = o.metrics.stage(stage)
+	}
+	return cfg
+}
@@ -0,0 +1,117 @@
+package hw06pipelineexecution
+
+import "sync"
+
+// orderedWindowPerWorker is the number of values per worker that may wait for a slower one in ordered mode.
+const orderedWindowPerWorker = 4
+
+// Parallel runs workers instances of the stage, spreading the values among them.
+// With ordered the values the stage sends for every received value go out in the order the values came in.
+// In this mode each value is passed to a separate call of the stage, so the stage must not keep state
+// between values, but it may send any number of values for every received one.
+func Parallel(stage Stage, workers int, ordered bool) Stage {
+	if workers <= 1 {
+		return stage
+	}
+	if ordered {
+		return func(in In) Out {
+			return parallelOrdered(stage, workers, in)
+		}
+	}
+	return func(in In) Out {
+		return parallelUnordered(stage, workers, in)
+	}
+}
+
+func parallelUnordered(stage Stage, workers int, in In) Out {
+	out := make(Bi)
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(workerOut Out) {
+			defer wg.Done()
+			for v := range workerOut {
+				out <- v
+			}
+		}(stage(in))
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func parallelOrdered(stage Stage, workers int, in In) Out {
+	type item struct {
+		seq   int
+		value interface{}
+	}
+	type result struct {
+		seq    int
+		values []interface{}
+	}
+
+	// slots bounds the values taken from in and not sent out yet, so a slow value
+	// holds back at most the window of values behind it.
+	slots := make(chan struct{}, workers*orderedWindowPerWorker)
+	items := make(chan item)
+	go func() {
+		defer close(items)
+		seq := 0
+		for v := range in {
+			slots <- struct{}{}
+			items <- item{seq: seq, value: v}
+			seq++
+		}
+	}()
+
+	results := make(chan result)
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for it := range items {
+				results <- result{seq: it.seq, values: runOnce(stage, it.value)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	out := make(Bi)
+	go func() {
+		defer close(out)
+		pending := make(map[int][]interface{}, cap(slots))
+		next := 0
+		for r := range results {
+			pending[r.seq] = r.values
+			for values, ok := pending[next]; ok; values, ok = pending[next] {
+				delete(pending, next)
+				for _, v := range values {
+					out <- v
+				}
+				<-slots
+				next++
+			}
+		}
+	}()
+	return out
+}
+
+// runOnce passes the single value to a new instance of the stage and returns everything it sends.
+func runOnce(stage Stage, v interface{}) []interface{} {
+	in := make(Bi, 1)
+	in <- v
+	close(in)
+
+	var values []interface{}
+	for out := range stage(in) {
+		values = append(values, out)
+	}
+	return values
+}
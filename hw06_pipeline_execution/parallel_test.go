@@ -0,0 +1,123 @@
+package hw06pipelineexecution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	slow := func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				// The greater values are processed faster to mix up the order.
+				time.Sleep(sleepPerStage / time.Duration(v.(int)+1))
+				out <- v.(int) * 10
+			}
+		}()
+		return out
+	}
+
+	produce := func(count int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for i := 0; i < count; i++ {
+				in <- i
+			}
+		}()
+		return in
+	}
+
+	collect := func(out Out) []int {
+		result := make([]int, 0, 10)
+		for v := range out {
+			result = append(result, v.(int))
+		}
+		return result
+	}
+
+	t.Run("single worker", func(t *testing.T) {
+		require.Equal(t, []int{0, 10, 20}, collect(Parallel(slow, 1, true)(produce(3))))
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		start := time.Now()
+		result := collect(ExecutePipeline(produce(8), nil, Parallel(slow, 8, false)))
+		elapsed := time.Since(start)
+
+		require.ElementsMatch(t, []int{0, 10, 20, 30, 40, 50, 60, 70}, result)
+		require.Less(t, int64(elapsed), int64(sleepPerStage)+int64(fault))
+	})
+
+	t.Run("ordered", func(t *testing.T) {
+		start := time.Now()
+		result := collect(ExecutePipeline(produce(8), nil, Parallel(slow, 4, true)))
+		elapsed := time.Since(start)
+
+		require.Equal(t, []int{0, 10, 20, 30, 40, 50, 60, 70}, result)
+		require.Less(t, int64(elapsed), int64(sleepPerStage)+int64(fault))
+	})
+
+	t.Run("ordered with filtering stage", func(t *testing.T) {
+		odd := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					if v.(int)%2 == 1 {
+						out <- v
+					}
+				}
+			}()
+			return out
+		}
+
+		require.Equal(t, []int{1, 3, 5, 7, 9}, collect(Parallel(odd, 3, true)(produce(10))))
+	})
+
+	t.Run("ordered with flat mapping stage", func(t *testing.T) {
+		twice := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					time.Sleep(time.Duration(10-v.(int)) * time.Millisecond)
+					out <- v
+					out <- v.(int) * 10
+				}
+			}()
+			return out
+		}
+
+		require.Equal(t, []int{0, 0, 1, 10, 2, 20, 3, 30}, collect(Parallel(twice, 4, true)(produce(4))))
+	})
+
+	t.Run("slow value does not stall workers", func(t *testing.T) {
+		firstSlow := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					if v.(int) == 0 {
+						time.Sleep(sleepPerStage)
+					} else {
+						time.Sleep(sleepPerStage / 10)
+					}
+					out <- v
+				}
+			}()
+			return out
+		}
+
+		start := time.Now()
+		result := collect(Parallel(firstSlow, 2, true)(produce(9)))
+		elapsed := time.Since(start)
+
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, result)
+		require.Less(t, int64(elapsed), int64(sleepPerStage)+int64(fault))
+	})
+}
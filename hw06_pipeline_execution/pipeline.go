@@ -1,5 +1,7 @@
 package hw06pipelineexecution
 
+import "context"
+
 type (
 	In  = <-chan interface{}
 	Out = In
@@ -13,28 +15,97 @@ func ExecutePipeline(in In, done In, stages ...Stage) Out {
 		return nil
 	}
 
+	return execStages(in, done, nil, stages, &options{})
+}
+
+// ExecutePipelineWithOptions works like ExecutePipeline with buffering and metrics set by opts.
+func ExecutePipelineWithOptions(in In, done In, stages []Stage, opts ...Option) Out {
+	if len(stages) == 0 {
+		return nil
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return execStages(in, done, nil, stages, o)
+}
+
+// ExecutePipelineContext works like ExecutePipeline but stops when ctx is done.
+// After that every goroutine of the pipeline exits even if nobody reads out anymore,
+// provided the stages exit when their input is closed. The sender to in should watch ctx by itself.
+func ExecutePipelineContext(ctx context.Context, in In, stages ...Stage) Out {
+	if len(stages) == 0 {
+		return nil
+	}
+	return execStage(func(in In) Out {
+		return in
+	}, execStages(in, nil, ctx.Done(), stages, &options{}), nil, ctx.Done(), stageConfig{drain: true})
+}
+
+func execStages(in In, done In, stop <-chan struct{}, stages []Stage, opts *options) Out {
+	if opts.metrics != nil {
+		opts.metrics.init(len(stages))
+	}
+
 	out := in
 	for i := range stages {
-		out = execStage(stages[i], out, done)
+		out = execStage(stages[i], out, done, stop, opts.stageConfig(i))
 	}
 	return out
 }
 
-func execStage(stage Stage, in In, done In) Out {
-	out := make(Bi)
+// stageConfig sets up the input channel of a stage.
+type stageConfig struct {
+	// drain makes execStage discard the rest of its input when stopped,
+	// so the goroutine sending to it does not block forever.
+	drain   bool
+	buffer  int
+	metrics *stageMetrics
+}
+
+// execStage passes values from in to the stage until in is closed or any of done and stop is closed.
+func execStage(stage Stage, in In, done In, stop <-chan struct{}, cfg stageConfig) Out {
+	out := make(Bi, cfg.buffer)
+	if cfg.metrics != nil {
+		cfg.metrics.start(out)
+	}
 	go func() {
-		defer close(out)
-		for {
-			select {
-			case <-done:
-				return
-			case data, ok := <-in:
-				if !ok {
-					return
-				}
-				out <- data
+		stopped := forward(in, out, done, stop, cfg.metrics)
+		close(out)
+		if stopped && cfg.drain {
+			for range in { //nolint:revive
 			}
 		}
 	}()
 	return stage(out)
 }
+
+// forward passes values from in to out and reports whether it was stopped before in was closed.
+// Time spent waiting for in and out is added to the metrics if they are set.
+func forward(in In, out Bi, done In, stop <-chan struct{}, m *stageMetrics) bool {
+	for {
+		waitStart := m.now()
+		select {
+		case <-done:
+			return true
+		case <-stop:
+			return true
+		case data, ok := <-in:
+			if !ok {
+				return false
+			}
+			m.received(waitStart)
+
+			waitStart = m.now()
+			select {
+			case <-done:
+				return true
+			case <-stop:
+				return true
+			case out <- data:
+			}
+			m.sent(waitStart)
+		}
+	}
+}
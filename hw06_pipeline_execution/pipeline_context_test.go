@@ -0,0 +1,115 @@
+package hw06pipelineexecution
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineContext(t *testing.T) {
+	// Stage generator that ignores cancellation and blocks on sending until somebody reads
+	g := func(f func(v interface{}) interface{}) Stage {
+		return func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					out <- f(v)
+				}
+			}()
+			return out
+		}
+	}
+	stages := []Stage{
+		g(func(v interface{}) interface{} { return v }),
+		g(func(v interface{}) interface{} { return v.(int) * 2 }),
+		g(func(v interface{}) interface{} { return v.(int) + 100 }),
+	}
+
+	produce := func(ctx context.Context) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for i := 0; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case in <- i:
+				}
+			}
+		}()
+		return in
+	}
+
+	waitGoroutines := func(t *testing.T, expected int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > expected && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		require.LessOrEqual(t, runtime.NumGoroutine(), expected, "goroutines leaked")
+	}
+
+	t.Run("empty stages", func(t *testing.T) {
+		require.Nil(t, ExecutePipelineContext(context.Background(), make(Bi)))
+	})
+
+	t.Run("simple case", func(t *testing.T) {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range []int{1, 2, 3} {
+				in <- v
+			}
+		}()
+
+		result := make([]interface{}, 0, 3)
+		for v := range ExecutePipelineContext(context.Background(), in, stages...) {
+			result = append(result, v)
+		}
+		require.Equal(t, []interface{}{102, 104, 106}, result)
+	})
+
+	t.Run("consumer stops reading", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		out := ExecutePipelineContext(ctx, produce(ctx), stages...)
+		for i := 0; i < 10; i++ {
+			<-out
+		}
+		cancel()
+
+		waitGoroutines(t, before)
+	})
+
+	t.Run("canceled before reading", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		out := ExecutePipelineContext(ctx, produce(ctx), stages...)
+		cancel()
+
+		waitGoroutines(t, before)
+		for range out { //nolint:revive
+		}
+	})
+
+	t.Run("done channel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(Bi)
+
+		out := ExecutePipeline(produce(ctx), done, stages...)
+		<-out
+		close(done)
+		cancel()
+		for range out { //nolint:revive
+		}
+
+		waitGoroutines(t, before)
+	})
+}
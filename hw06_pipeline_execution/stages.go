@@ -0,0 +1,346 @@
+package hw06pipelineexecution
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidStageArgument is the value of the panic of a stage constructor called with a wrong argument.
+var ErrInvalidStageArgument = errors.New("invalid stage argument")
+
+func mustBePositive[T int | float64 | time.Duration](stage, name string, v T) {
+	if !(v > 0) { // NaN is not positive either.
+		panic(fmt.Errorf("%w: %s %s must be positive, got %v", ErrInvalidStageArgument, stage, name, v))
+	}
+}
+
+// Map sends the result of f for every value.
+func Map(done In, f func(v interface{}) interface{}) Stage {
+	return newStage(func(in In, out Bi) {
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			if !send(done, out, f(v)) {
+				return
+			}
+		}
+	})
+}
+
+// Filter sends only the values for which keep returns true.
+func Filter(done In, keep func(v interface{}) bool) Stage {
+	return newStage(func(in In, out Bi) {
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			if keep(v) && !send(done, out, v) {
+				return
+			}
+		}
+	})
+}
+
+// FlatMap sends every value of the slice returned by f.
+func FlatMap(done In, f func(v interface{}) []interface{}) Stage {
+	return newStage(func(in In, out Bi) {
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			for _, item := range f(v) {
+				if !send(done, out, item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Dedup drops the values whose key has already been seen, nil key means the value itself.
+// Keys must be comparable and are kept until the input is closed.
+func Dedup(done In, key func(v interface{}) interface{}) Stage {
+	if key == nil {
+		key = func(v interface{}) interface{} { return v }
+	}
+	return newStage(func(in In, out Bi) {
+		seen := make(map[interface{}]struct{})
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			k := key(v)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !send(done, out, v) {
+				return
+			}
+		}
+	})
+}
+
+// Tee sends every value to all sides before passing it further. The sides are closed with the output.
+func Tee(done In, sides ...Bi) Stage {
+	return newStage(func(in In, out Bi) {
+		defer func() {
+			for _, side := range sides {
+				close(side)
+			}
+		}()
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			for _, side := range sides {
+				if !send(done, side, v) {
+					return
+				}
+			}
+			if !send(done, out, v) {
+				return
+			}
+		}
+	})
+}
+
+// Batch sends values in slices of size, a smaller slice is sent when maxWait passed since its first value.
+// It panics if size or maxWait is not positive.
+func Batch(done In, size int, maxWait time.Duration) Stage {
+	mustBePositive("Batch", "size", size)
+	mustBePositive("Batch", "maxWait", maxWait)
+	return newStage(func(in In, out Bi) {
+		batch := make([]interface{}, 0, size)
+		timer := time.NewTimer(maxWait)
+		stopTimer(timer)
+		defer timer.Stop()
+
+		flush := func() bool {
+			stopTimer(timer)
+			if len(batch) == 0 {
+				return true
+			}
+			ok := send(done, out, batch)
+			batch = make([]interface{}, 0, size)
+			return ok
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 {
+					timer.Reset(maxWait)
+				}
+				if len(batch) >= size && !flush() {
+					return
+				}
+			}
+		}
+	})
+}
+
+// TumblingWindow sends the values received during every period of size as a slice, empty windows are skipped.
+// It panics if size is not positive.
+func TumblingWindow(done In, size time.Duration) Stage {
+	mustBePositive("TumblingWindow", "size", size)
+	return newStage(func(in In, out Bi) {
+		var window []interface{}
+		ticker := time.NewTicker(size)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if len(window) > 0 && !send(done, out, window) {
+					return
+				}
+				window = nil
+			case v, ok := <-in:
+				if !ok {
+					if len(window) > 0 {
+						send(done, out, window)
+					}
+					return
+				}
+				window = append(window, v)
+			}
+		}
+	})
+}
+
+// SlidingWindow sends the values received during the last size as a slice every step, empty windows are skipped.
+// The last window is sent when the input is closed. It panics if size or step is not positive.
+func SlidingWindow(done In, size, step time.Duration) Stage {
+	mustBePositive("SlidingWindow", "size", size)
+	mustBePositive("SlidingWindow", "step", step)
+
+	type entry struct {
+		at time.Time
+		v  interface{}
+	}
+
+	return newStage(func(in In, out Bi) {
+		var entries []entry
+		ticker := time.NewTicker(step)
+		defer ticker.Stop()
+
+		// flush sends the values received during size before now.
+		flush := func(now time.Time) bool {
+			start := 0
+			for start < len(entries) && now.Sub(entries[start].at) > size {
+				start++
+			}
+			entries = entries[start:]
+			if len(entries) == 0 {
+				return true
+			}
+
+			window := make([]interface{}, 0, len(entries))
+			for _, e := range entries {
+				window = append(window, e.v)
+			}
+			return send(done, out, window)
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if !flush(now) {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush(time.Now())
+					return
+				}
+				entries = append(entries, entry{at: time.Now(), v: v})
+			}
+		}
+	})
+}
+
+// Throttle sends no more than perSecond values per second, delaying the rest.
+// It panics if perSecond is not positive.
+func Throttle(done In, perSecond float64) Stage {
+	mustBePositive("Throttle", "perSecond", perSecond)
+	interval := time.Duration(float64(time.Second) / perSecond)
+	return newStage(func(in In, out Bi) {
+		var next time.Time
+		for {
+			v, ok := receive(done, in)
+			if !ok {
+				return
+			}
+			if wait := time.Until(next); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-done:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			next = time.Now().Add(interval)
+			if !send(done, out, v) {
+				return
+			}
+		}
+	})
+}
+
+// Debounce sends the last value once no new values are received for wait.
+// It panics if wait is not positive.
+func Debounce(done In, wait time.Duration) Stage {
+	mustBePositive("Debounce", "wait", wait)
+	return newStage(func(in In, out Bi) {
+		var last interface{}
+		var pending bool
+		timer := time.NewTimer(wait)
+		stopTimer(timer)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+				pending = false
+				if !send(done, out, last) {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						send(done, out, last)
+					}
+					return
+				}
+				last, pending = v, true
+				stopTimer(timer)
+				timer.Reset(wait)
+			}
+		}
+	})
+}
+
+func newStage(run func(in In, out Bi)) Stage {
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			run(in, out)
+		}()
+		return out
+	}
+}
+
+// receive returns the next value of in, or false when in or done is closed.
+func receive(done In, in In) (interface{}, bool) {
+	select {
+	case <-done:
+		return nil, false
+	case v, ok := <-in:
+		return v, ok
+	}
+}
+
+func send(done In, out Bi, v interface{}) bool {
+	select {
+	case <-done:
+		return false
+	case out <- v:
+		return true
+	}
+}
+
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
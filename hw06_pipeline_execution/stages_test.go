@@ -0,0 +1,176 @@
+package hw06pipelineexecution
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStages(t *testing.T) {
+	produce := func(data ...interface{}) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				in <- v
+			}
+		}()
+		return in
+	}
+
+	// produceEvery sends the values with the pause between them.
+	produceEvery := func(pause time.Duration, data ...interface{}) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				in <- v
+				time.Sleep(pause)
+			}
+		}()
+		return in
+	}
+
+	collect := func(out Out) []interface{} {
+		result := make([]interface{}, 0, 10)
+		for v := range out {
+			result = append(result, v)
+		}
+		return result
+	}
+
+	t.Run("map, filter and flat map", func(t *testing.T) {
+		out := ExecutePipeline(produce(1, 2, 3, 4), nil,
+			Filter(nil, func(v interface{}) bool { return v.(int)%2 == 0 }),
+			Map(nil, func(v interface{}) interface{} { return v.(int) * 10 }),
+			FlatMap(nil, func(v interface{}) []interface{} { return []interface{}{v, v.(int) + 1} }),
+		)
+		require.Equal(t, []interface{}{20, 21, 40, 41}, collect(out))
+	})
+
+	t.Run("dedup", func(t *testing.T) {
+		require.Equal(t, []interface{}{1, 2, 3}, collect(Dedup(nil, nil)(produce(1, 2, 1, 3, 2))))
+
+		byLength := Dedup(nil, func(v interface{}) interface{} { return len(v.(string)) })
+		require.Equal(t, []interface{}{"a", "bb"}, collect(byLength(produce("a", "bb", "c", "dd"))))
+	})
+
+	t.Run("tee", func(t *testing.T) {
+		side := make(Bi)
+		var sideResult []interface{}
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sideResult = collect(side)
+		}()
+
+		require.Equal(t, []interface{}{1, 2, 3}, collect(Tee(nil, side)(produce(1, 2, 3))))
+		wg.Wait()
+		require.Equal(t, []interface{}{1, 2, 3}, sideResult)
+	})
+
+	t.Run("batch by size", func(t *testing.T) {
+		out := Batch(nil, 2, time.Second)(produce(1, 2, 3, 4, 5))
+		require.Equal(t, []interface{}{
+			[]interface{}{1, 2},
+			[]interface{}{3, 4},
+			[]interface{}{5},
+		}, collect(out))
+	})
+
+	t.Run("batch by time", func(t *testing.T) {
+		out := Batch(nil, 100, 20*time.Millisecond)(produceEvery(50*time.Millisecond, 1, 2))
+		require.Equal(t, []interface{}{
+			[]interface{}{1},
+			[]interface{}{2},
+		}, collect(out))
+	})
+
+	t.Run("tumbling window", func(t *testing.T) {
+		out := TumblingWindow(nil, 50*time.Millisecond)(produceEvery(30*time.Millisecond, 1, 2, 3, 4))
+		windows := collect(out)
+
+		values := make([]interface{}, 0, 4)
+		for _, w := range windows {
+			values = append(values, w.([]interface{})...)
+		}
+		require.Equal(t, []interface{}{1, 2, 3, 4}, values)
+		require.Greater(t, len(windows), 1)
+	})
+
+	t.Run("sliding window", func(t *testing.T) {
+		out := SlidingWindow(nil, 100*time.Millisecond, 20*time.Millisecond)(produceEvery(30*time.Millisecond, 1, 2))
+		windows := collect(out)
+
+		require.NotEmpty(t, windows)
+		require.Contains(t, windows, []interface{}{1, 2})
+	})
+
+	t.Run("sliding window flushes on close", func(t *testing.T) {
+		out := SlidingWindow(nil, time.Hour, time.Hour)(produce(1, 2, 3))
+		require.Equal(t, []interface{}{[]interface{}{1, 2, 3}}, collect(out))
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		for name, create := range map[string]func(){
+			"batch size":      func() { Batch(nil, 0, time.Second) },
+			"negative batch":  func() { Batch(nil, -1, time.Second) },
+			"batch wait":      func() { Batch(nil, 10, 0) },
+			"tumbling window": func() { TumblingWindow(nil, 0) },
+			"sliding window":  func() { SlidingWindow(nil, time.Second, -time.Second) },
+			"throttle":        func() { Throttle(nil, 0) },
+			"negative rate":   func() { Throttle(nil, -5) },
+			"NaN rate":        func() { Throttle(nil, math.NaN()) },
+			"debounce":        func() { Debounce(nil, 0) },
+			"negative wait":   func() { Debounce(nil, -time.Second) },
+		} {
+			create := create
+			t.Run(name, func(t *testing.T) {
+				defer func() {
+					err, ok := recover().(error)
+					require.True(t, ok)
+					require.ErrorIs(t, err, ErrInvalidStageArgument)
+				}()
+				create()
+			})
+		}
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		start := time.Now()
+		require.Equal(t, []interface{}{1, 2, 3, 4}, collect(Throttle(nil, 50)(produce(1, 2, 3, 4))))
+		require.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	})
+
+	t.Run("debounce", func(t *testing.T) {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			time.Sleep(50 * time.Millisecond)
+			in <- 3
+			in <- 4
+		}()
+		require.Equal(t, []interface{}{2, 4}, collect(Debounce(nil, 20*time.Millisecond)(in)))
+	})
+
+	t.Run("done", func(t *testing.T) {
+		done := make(Bi)
+		in := make(Bi)
+		out := ExecutePipeline(in, done,
+			Map(done, func(v interface{}) interface{} { return v }),
+			Batch(done, 10, time.Hour),
+			Debounce(done, time.Hour),
+		)
+		in <- 1
+		close(done)
+
+		require.Empty(t, collect(out))
+		close(in)
+	})
+}
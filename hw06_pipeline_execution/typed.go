@@ -0,0 +1,99 @@
+package hw06pipelineexecution
+
+// TypedStage is a stage that receives values of type I and sends values of type O.
+type TypedStage[I, O any] func(in <-chan I) (out <-chan O)
+
+// Chain is a sequence of stages turning values of type I into values of type O.
+// Chains are built with Pipe, Then and PipeN, so stages of incompatible types do not compile.
+type Chain[I, O any] struct {
+	stages []Stage
+}
+
+// Pipe makes a chain of a single stage.
+func Pipe[I, O any](stage TypedStage[I, O]) Chain[I, O] {
+	return Chain[I, O]{stages: []Stage{Untyped(stage)}}
+}
+
+// Then appends the stage to the chain.
+func Then[I, M, O any](chain Chain[I, M], stage TypedStage[M, O]) Chain[I, O] {
+	stages := make([]Stage, 0, len(chain.stages)+1)
+	stages = append(stages, chain.stages...)
+	return Chain[I, O]{stages: append(stages, Untyped(stage))}
+}
+
+func Pipe2[A, B, C any](s1 TypedStage[A, B], s2 TypedStage[B, C]) Chain[A, C] {
+	return Then(Pipe(s1), s2)
+}
+
+func Pipe3[A, B, C, D any](s1 TypedStage[A, B], s2 TypedStage[B, C], s3 TypedStage[C, D]) Chain[A, D] {
+	return Then(Pipe2(s1, s2), s3)
+}
+
+func Pipe4[A, B, C, D, E any](
+	s1 TypedStage[A, B], s2 TypedStage[B, C], s3 TypedStage[C, D], s4 TypedStage[D, E],
+) Chain[A, E] {
+	return Then(Pipe3(s1, s2, s3), s4)
+}
+
+// Stages returns the untyped stages of the chain to be passed to ExecutePipeline.
+func (c Chain[I, O]) Stages() []Stage {
+	return c.stages
+}
+
+// Untyped adapts the typed stage to be used in ExecutePipeline.
+func Untyped[I, O any](stage TypedStage[I, O]) Stage {
+	return func(in In) Out {
+		return toUntyped(stage(fromUntyped[I](in, nil)), nil)
+	}
+}
+
+// ExecuteTyped runs the chain with ExecutePipeline.
+func ExecuteTyped[I, O any](in <-chan I, done In, chain Chain[I, O]) <-chan O {
+	if len(chain.stages) == 0 {
+		return nil
+	}
+	return fromUntyped[O](ExecutePipeline(toUntyped(in, done), done, chain.stages...), done)
+}
+
+// toUntyped passes values from in to the returned channel until in is closed or done is closed.
+func toUntyped[T any](in <-chan T, done In) Out {
+	out := make(Bi)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// fromUntyped passes values from in to the returned channel until in is closed.
+// Once done is closed the rest of in is discarded, so the stages sending to it do not block.
+func fromUntyped[T any](in In, done In) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			select {
+			case <-done:
+				for range in { //nolint:revive
+				}
+				return
+			case out <- v.(T):
+			}
+		}
+	}()
+	return out
+}
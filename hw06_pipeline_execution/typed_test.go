@@ -0,0 +1,129 @@
+package hw06pipelineexecution
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedPipeline(t *testing.T) {
+	// Typed stage generator
+	g := func(f func(v int) int) TypedStage[int, int] {
+		return func(in <-chan int) <-chan int {
+			out := make(chan int)
+			go func() {
+				defer close(out)
+				for v := range in {
+					time.Sleep(sleepPerStage)
+					out <- f(v)
+				}
+			}()
+			return out
+		}
+	}
+	stringifier := func(in <-chan int) <-chan string {
+		out := make(chan string)
+		go func() {
+			defer close(out)
+			for v := range in {
+				time.Sleep(sleepPerStage)
+				out <- strconv.Itoa(v)
+			}
+		}()
+		return out
+	}
+
+	chain := Pipe4(
+		g(func(v int) int { return v }),
+		g(func(v int) int { return v * 2 }),
+		g(func(v int) int { return v + 100 }),
+		stringifier,
+	)
+
+	t.Run("empty chain", func(t *testing.T) {
+		require.Nil(t, ExecuteTyped(make(chan int), nil, Chain[int, string]{}))
+	})
+
+	t.Run("simple case", func(t *testing.T) {
+		in := make(chan int)
+		data := []int{1, 2, 3, 4, 5}
+
+		go func() {
+			for _, v := range data {
+				in <- v
+			}
+			close(in)
+		}()
+
+		result := make([]string, 0, 10)
+		start := time.Now()
+		for s := range ExecuteTyped(in, nil, chain) {
+			result = append(result, s)
+		}
+		elapsed := time.Since(start)
+
+		require.Equal(t, []string{"102", "104", "106", "108", "110"}, result)
+		require.Less(t,
+			int64(elapsed),
+			int64(sleepPerStage)*int64(len(chain.Stages())+len(data)-1)+int64(fault))
+	})
+
+	t.Run("then", func(t *testing.T) {
+		in := make(chan int)
+		go func() {
+			in <- 1
+			close(in)
+		}()
+
+		result := make([]string, 0, 1)
+		for s := range ExecuteTyped(in, nil, Then(Pipe(g(func(v int) int { return v * 3 })), stringifier)) {
+			result = append(result, s)
+		}
+		require.Equal(t, []string{"3"}, result)
+	})
+
+	t.Run("done case", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		in := make(chan int)
+		done := make(Bi)
+		go func() {
+			defer close(in)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				case in <- i:
+				}
+			}
+		}()
+
+		out := ExecuteTyped(in, done, chain)
+		<-out
+		close(done)
+		for range out { //nolint:revive
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		require.LessOrEqual(t, runtime.NumGoroutine(), before, "goroutines leaked")
+	})
+
+	t.Run("untyped stages", func(t *testing.T) {
+		in := make(Bi)
+		go func() {
+			in <- 5
+			close(in)
+		}()
+
+		result := make([]interface{}, 0, 1)
+		for s := range ExecutePipeline(in, nil, chain.Stages()...) {
+			result = append(result, s)
+		}
+		require.Equal(t, []interface{}{"110"}, result)
+	})
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"time"
+	"runtime"
+	"strconv"
+	"strings"
 
-	"github.com/cheggaaa/pb"
+	"github.com/cespare/xxhash/v2"
 )
 
 var (
@@ -15,66 +25,623 @@ var (
 	ErrFromPathEmpty         = errors.New("from file path is empty")
 	ErrToPathEmpty           = errors.New("to file path is empty")
 	ErrFromAndToPathsEqual   = errors.New("from and to paths are equal")
+	ErrDestinationMismatch   = errors.New("destination does not match source")
+	ErrAtomicResume          = errors.New("atomic copy can not be resumed")
+	ErrAtomicInPlace         = errors.New("atomic copy can not be in place")
+	ErrUnknownHash           = errors.New("unknown hash algorithm")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrCopyIntoItself        = errors.New("can not copy into itself")
+	ErrUnsupportedSeek       = errors.New("operation requires a seekable regular file")
 )
 
-func Copy(fromPath, toPath string, offset, limit int64) error {
+const (
+	// tailBlockSize is the size of the last copied block compared with the source on resume.
+	tailBlockSize = 64 * 1024
+	// stdPath stands for stdin as the source and for stdout as the destination.
+	stdPath = "-"
+)
+
+type Option func(*options)
+
+type options struct {
+	ctx       context.Context
+	resume    bool
+	checkTail bool
+	atomic    bool
+	inPlace   bool
+	checksum  *Checksum
+	hash      hash.Hash
+	// noZeroCopy makes execCopy always copy through user space.
+	noZeroCopy bool
+
+	include, exclude []string
+	symlinks         SymlinkPolicy
+	preserve         bool
+	dryRun           io.Writer
+
+	rng      *Range
+	parallel int
+	progress ProgressReporter
+}
+
+// Checksum describes the verification of the copied range.
+type Checksum struct {
+	// Algorithm is one of sha256, crc32c and xxhash.
+	Algorithm string
+	// Expected is the hex digest the copy must have, empty means any.
+	Expected string
+	// Sum is the hex digest of the copy set by Copy.
+	Sum string
+}
+
+// WithChecksum computes the digest of the copied range while copying and compares it with the expected one.
+func WithChecksum(c *Checksum) Option {
+	return func(o *options) {
+		o.checksum = c
+	}
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return sha256.New(), nil
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case "xxhash":
+		return xxhash.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHash, algorithm)
+	}
+}
+
+// verify sets the digest of the copy and compares it with the expected one.
+func (o *options) verify() error {
+	if o.checksum == nil {
+		return nil
+	}
+
+	o.checksum.Sum = hex.EncodeToString(o.hash.Sum(nil))
+	if o.checksum.Expected != "" && !strings.EqualFold(o.checksum.Expected, o.checksum.Sum) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, o.checksum.Expected, o.checksum.Sum)
+	}
+	return nil
+}
+
+// WithContext stops the copy when ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithAtomic requires the copy to be atomic, failing for destinations that can not be replaced by rename.
+// A copy to a regular or a missing destination that is not resumed is atomic by default:
+// it is written to a temporary file next to the destination and renamed on success,
+// so the destination is never left partially written.
+func WithAtomic() Option {
+	return func(o *options) {
+		o.atomic = true
+	}
+}
+
+// WithInPlace truncates the destination and writes the copy into it instead of renaming a temporary file.
+func WithInPlace() Option {
+	return func(o *options) {
+		o.inPlace = true
+	}
+}
+
+// WithResume continues the copy from the end of the existing destination file.
+// With checkTail the last copied block of the destination is compared with the source first.
+func WithResume(checkTail bool) Option {
+	return func(o *options) {
+		o.resume = true
+		o.checkTail = checkTail
+	}
+}
+
+func Copy(fromPath, toPath string, offset, limit int64, opts ...Option) error {
 	if err := validateFilePaths(fromPath, toPath); err != nil {
 		return err
 	}
 
-	fromStat, err := os.Stat(fromPath)
+	o, err := newOptions(opts)
 	if err != nil {
 		return err
 	}
-	fromSize := fromStat.Size()
-	if offset > fromSize {
-		return ErrOffsetExceedsFileSize
+	if o.rng != nil && (offset != 0 || limit != 0) {
+		return fmt.Errorf("%w: range with offset or limit", ErrInvalidRange)
+	}
+	if o.checksum != nil {
+		if o.hash, err = newHash(o.checksum.Algorithm); err != nil {
+			return err
+		}
 	}
+	return copyFile(o, fromPath, toPath, offset, limit)
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{ctx: context.Background(), progress: SilentReporter{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.atomic && o.resume {
+		return nil, ErrAtomicResume
+	}
+	if o.atomic && o.inPlace {
+		return nil, ErrAtomicInPlace
+	}
+	if o.parallel < 0 {
+		return nil, ErrWrongParallelism
+	}
+	return o, nil
+}
 
-	fromFile, err := os.Open(fromPath)
+func copyFile(o *options, fromPath, toPath string, offset, limit int64) error {
+	fromFile, err := openSource(o.ctx, fromPath)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = fromFile.Close()
+	defer closeFile(fromFile)
+
+	fromStat, err := fromFile.Stat()
+	if err != nil {
+		return err
+	}
+	if !fromStat.Mode().IsRegular() {
+		return copyStream(o, fromFile, toPath, offset, limit)
+	}
+
+	offset, copySize, err := o.span(offset, limit, fromStat.Size())
+	if err != nil {
+		return err
+	}
+	atomic, err := o.atomicDest(toPath)
+	if err != nil {
+		return err
+	}
+	if atomic {
+		return copyAtomic(o, toPath, fromStat.Mode().Perm(), func(tmp *os.File) error {
+			return execCopy(o, fromFile, tmp, offset, copySize)
+		})
+	}
+
+	var toFile *os.File
+	var copied int64
+	if o.resume {
+		toFile, copied, err = openResumed(fromFile, toPath, offset, copySize, o.checkTail)
+	} else {
+		toFile, err = createDest(o.ctx, toPath)
+	}
+	if err != nil {
+		return err
+	}
+	defer closeFile(toFile)
+
+	if o.hash != nil && copied > 0 {
+		if _, err = io.Copy(o.hash, io.NewSectionReader(toFile, 0, copied)); err != nil {
+			return err
+		}
+	}
+	if err = execCopy(o, fromFile, toFile, offset+copied, copySize-copied); err != nil {
+		return err
+	}
+	return o.verify()
+}
+
+// copyStream copies a source of unknown size, skipping offset bytes by reading them
+// and copying until EOF or limit bytes if limit is set.
+func copyStream(o *options, source *os.File, toPath string, offset, limit int64) error {
+	if o.resume {
+		return fmt.Errorf("%w: resume from %s", ErrUnsupportedSeek, source.Name())
+	}
+	if o.parallel > 1 {
+		return fmt.Errorf("%w: parallel copy from %s", ErrUnsupportedSeek, source.Name())
+	}
+	offset, limit, err := o.streamSpan(offset, limit)
+	if err != nil {
+		return err
+	}
+
+	reader := newInterruptibleReader(o.ctx, source)
+	defer reader.Close()
+
+	if offset > 0 {
+		_, err := io.CopyN(io.Discard, reader, offset)
+		if errors.Is(err, io.EOF) {
+			return ErrOffsetExceedsFileSize
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	atomic, err := o.atomicDest(toPath)
+	if err != nil {
+		return err
+	}
+	if atomic {
+		return copyAtomic(o, toPath, 0, func(tmp *os.File) error {
+			return execStream(o, reader, tmp, limit)
+		})
+	}
+
+	toFile, err := createDest(o.ctx, toPath)
+	if err != nil {
+		return err
+	}
+	defer closeFile(toFile)
+
+	if err = execStream(o, reader, toFile, limit); err != nil {
+		return err
+	}
+	return o.verify()
+}
+
+// openSource opens the file at path, or returns stdin for stdPath.
+func openSource(ctx context.Context, path string) (*os.File, error) {
+	if path == stdPath {
+		return os.Stdin, nil
+	}
+	return openInterruptible(ctx, func() (*os.File, error) {
+		return os.Open(path)
+	})
+}
+
+// closeFile closes the file unless it is stdin or stdout.
+func closeFile(file *os.File) {
+	if file != os.Stdin && file != os.Stdout {
+		_ = file.Close()
+	}
+}
+
+// createDest creates or truncates the file at path, or returns stdout for stdPath.
+func createDest(ctx context.Context, path string) (*os.File, error) {
+	if path == stdPath {
+		return os.Stdout, nil
+	}
+	return openInterruptible(ctx, func() (*os.File, error) {
+		return os.Create(path)
+	})
+}
+
+// openInterruptible returns once ctx is done even if open blocks, as opening a FIFO does
+// until its other side is opened. The file opened after that is closed.
+func openInterruptible(ctx context.Context, open func() (*os.File, error)) (*os.File, error) {
+	if ctx.Done() == nil {
+		return open()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		file *os.File
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		file, err := open()
+		opened <- result{file: file, err: err}
 	}()
 
-	toFile, err := os.Create(toPath)
+	select {
+	case r := <-opened:
+		return r.file, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-opened; r.err == nil {
+				_ = r.file.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// atomicDest reports whether the copy to toPath goes through a temporary file.
+// Stdout, devices and pipes are written in place unless WithAtomic requires the atomic copy.
+func (o *options) atomicDest(toPath string) (bool, error) {
+	if o.resume || o.inPlace {
+		return false, nil
+	}
+
+	replaceable := toPath != stdPath
+	if toStat, err := os.Stat(toPath); replaceable && err == nil {
+		replaceable = toStat.Mode().IsRegular()
+	}
+	if !replaceable && o.atomic {
+		return false, fmt.Errorf("%w: atomic copy to %s", ErrUnsupportedSeek, toPath)
+	}
+	return replaceable, nil
+}
+
+// copyAtomic calls write with a temporary file and renames it to toPath.
+// The copy keeps the permissions of the replaced destination, or perm if there is no destination yet.
+// A perm of 0 creates the copy with the permissions of os.Create, so the umask applies.
+// A symbolic link at toPath is kept and the file it points to is replaced.
+func copyAtomic(o *options, toPath string, perm os.FileMode, write func(tmp *os.File) error) error {
+	if target, err := filepath.EvalSymlinks(toPath); err == nil {
+		toPath = target
+	}
+	if toStat, err := os.Stat(toPath); err == nil {
+		perm = toStat.Mode().Perm()
+	}
+
+	tmp, err := createTemp(filepath.Dir(toPath), "."+filepath.Base(toPath)+".", ".tmp")
 	if err != nil {
 		return err
 	}
+	committed := false
 	defer func() {
-		_ = toFile.Close()
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
 	}()
 
-	return execCopy(fromFile, toFile, offset, limit, fromSize)
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = o.verify(); err != nil {
+		return err
+	}
+	if perm != 0 {
+		if err = tmp.Chmod(perm); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), toPath); err != nil {
+		return err
+	}
+	committed = true
+	return syncDir(filepath.Dir(toPath))
+}
+
+// createTemp creates a new file named prefix, a random number and suffix in dir.
+// Unlike os.CreateTemp, the file is created with the permissions of os.Create.
+func createTemp(dir, prefix, suffix string) (*os.File, error) {
+	for try := 0; try < 10000; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix) //nolint:gosec
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if !errors.Is(err, os.ErrExist) {
+			return file, err
+		}
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"+suffix), Err: os.ErrExist}
+}
+
+// syncDir makes the renaming of an entry of the directory durable.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		// Directories can not be synced on Windows, renaming is durable there.
+		return nil
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err = dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+	return dir.Close()
+}
+
+func calcCopySize(offset, limit, size int64) int64 {
+	if limit == 0 || limit > size-offset {
+		return size - offset
+	}
+	return limit
 }
 
-func execCopy(source, dest *os.File, offset, limit, size int64) error {
-	var copySize int64
-	switch {
-	case limit == 0 || limit > size-offset:
-		copySize = size - offset
-	case limit <= size-offset:
-		copySize = limit
+// openResumed opens the destination for appending the rest of the copy and returns the number of bytes copied before.
+func openResumed(source *os.File, toPath string, offset, copySize int64, checkTail bool) (*os.File, int64, error) {
+	if toPath == stdPath {
+		return nil, 0, fmt.Errorf("%w: resume to stdout", ErrUnsupportedSeek)
+	}
+	dest, err := os.OpenFile(toPath, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !regular(dest) {
+		_ = dest.Close()
+		return nil, 0, fmt.Errorf("%w: resume to %s", ErrUnsupportedSeek, toPath)
+	}
+
+	copied, err := checkResumed(source, dest, offset, copySize, checkTail)
+	if err == nil {
+		_, err = dest.Seek(copied, io.SeekStart)
+	}
+	if err != nil {
+		_ = dest.Close()
+		return nil, 0, err
 	}
+	return dest, copied, nil
+}
 
-	bar := pb.New(int(copySize)).SetUnits(pb.U_BYTES).SetRefreshRate(time.Millisecond)
-	bar.ShowSpeed = true
-	bar.Start()
-	defer bar.Finish()
+func checkResumed(source, dest *os.File, offset, copySize int64, checkTail bool) (int64, error) {
+	destStat, err := dest.Stat()
+	if err != nil {
+		return 0, err
+	}
+	copied := destStat.Size()
+	if copied > copySize {
+		return 0, ErrDestinationMismatch
+	}
+	if !checkTail || copied == 0 {
+		return copied, nil
+	}
+
+	blockSize := int64(tailBlockSize)
+	if copied < blockSize {
+		blockSize = copied
+	}
+	destSum, err := blockSum(dest, copied-blockSize, blockSize)
+	if err != nil {
+		return 0, err
+	}
+	sourceSum, err := blockSum(source, offset+copied-blockSize, blockSize)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(destSum, sourceSum) {
+		return 0, ErrDestinationMismatch
+	}
+	return copied, nil
+}
+
+func blockSum(r io.ReaderAt, offset, size int64) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, offset, size)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
 
-	reader := bar.NewProxyReader(source)
-	if _, err := source.Seek(offset, io.SeekStart); err != nil {
+func execCopy(o *options, source, dest *os.File, offset, copySize int64) (err error) {
+	o.progress.Start(copySize)
+	defer func() {
+		o.progress.Finish(err)
+	}()
+
+	if o.parallel > 1 {
+		return execParallel(o, source, dest, offset, copySize, o.progress.Add)
+	}
+	if o.hash == nil && !o.noZeroCopy && regular(source) && regular(dest) {
+		var done bool
+		if done, err = copyZero(o, source, dest, offset, copySize, o.progress.Add); done || err != nil {
+			return err
+		}
+	}
+
+	if _, err = source.Seek(offset, io.SeekStart); err != nil {
 		return err
 	}
-	_, err := io.CopyN(dest, reader, copySize)
+	reader := &progressReader{progress: o.progress, r: &ctxReader{ctx: o.ctx, r: source}}
+	_, err = io.CopyN(o.writer(dest), reader, copySize)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
 	return nil
 }
 
+// execStream copies source to dest until EOF, or until limit bytes are copied if limit is set.
+func execStream(o *options, source io.Reader, dest *os.File, limit int64) (err error) {
+	o.progress.Start(0)
+	defer func() {
+		o.progress.Finish(err)
+	}()
+
+	reader := source
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit)
+	}
+	_, err = io.Copy(o.writer(dest), &progressReader{progress: o.progress, r: reader})
+	return err
+}
+
+// writer returns dest teed to the checksum hash if there is one.
+func (o *options) writer(dest io.Writer) io.Writer {
+	if o.hash == nil {
+		return dest
+	}
+	return io.MultiWriter(dest, o.hash)
+}
+
+func regular(file *os.File) bool {
+	stat, err := file.Stat()
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// progressReader reports the bytes read.
+type progressReader struct {
+	progress ProgressReporter
+	r        io.Reader
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.progress.Add(int64(n))
+	return n, err
+}
+
+// interruptibleReader reads in a separate goroutine, so Read returns once ctx is done
+// even if the read blocks, as reads from pipes and terminals do.
+type interruptibleReader struct {
+	ctx     context.Context
+	r       io.Reader
+	buf     []byte
+	reqs    chan int
+	results chan readResult
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func newInterruptibleReader(ctx context.Context, r io.Reader) *interruptibleReader {
+	ir := &interruptibleReader{
+		ctx:     ctx,
+		r:       r,
+		buf:     make([]byte, 32*1024),
+		reqs:    make(chan int, 1),
+		results: make(chan readResult, 1),
+	}
+	go ir.run()
+	return ir
+}
+
+func (r *interruptibleReader) run() {
+	for size := range r.reqs {
+		n, err := r.r.Read(r.buf[:size])
+		r.results <- readResult{n: n, err: err}
+	}
+}
+
+func (r *interruptibleReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > len(r.buf) {
+		p = p[:len(r.buf)]
+	}
+
+	// The buffer is owned by the reading goroutine until its result is received,
+	// a read abandoned on ctx done never returns its data.
+	r.reqs <- len(p)
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	case res := <-r.results:
+		return copy(p, r.buf[:res.n]), res.err
+	}
+}
+
+// Close stops the reading goroutine once its current read returns.
+func (r *interruptibleReader) Close() {
+	close(r.reqs)
+}
+
+// ctxReader fails reading once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
 func validateFilePaths(fromPath, toPath string) error {
 	if len(fromPath) == 0 {
 		return ErrFromPathEmpty
@@ -83,12 +650,16 @@ func validateFilePaths(fromPath, toPath string) error {
 		return ErrToPathEmpty
 	}
 
-	fromAbs, err := filepath.Abs(fromPath)
+	if fromPath == stdPath || toPath == stdPath {
+		return nil
+	}
+
+	fromAbs, err := resolvePath(fromPath)
 	if err != nil {
 		return err
 	}
 
-	toAbs, err := filepath.Abs(toPath)
+	toAbs, err := resolvePath(toPath)
 	if err != nil {
 		return err
 	}
@@ -96,5 +667,33 @@ func validateFilePaths(fromPath, toPath string) error {
 	if fromAbs == toAbs {
 		return ErrFromAndToPathsEqual
 	}
+	if strings.HasPrefix(toAbs, fromAbs+string(filepath.Separator)) {
+		return ErrCopyIntoItself
+	}
 	return nil
 }
+
+// resolvePath returns the absolute path with the symbolic links of its longest existing part resolved,
+// so paths reaching the same file through links are equal.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing, rest := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+		existing, rest = parent, filepath.Join(filepath.Base(existing), rest)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build bench
+// +build bench
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -bench=. -benchmem -run=BenchmarkCopy -tags=bench
+
+func BenchmarkCopy(b *testing.B) {
+	fromFile := filepath.Join(b.TempDir(), "input.bin")
+	require.NoError(b, os.WriteFile(fromFile, make([]byte, 64*1024*1024), 0o600))
+	toFile := filepath.Join(b.TempDir(), "out.bin")
+
+	b.Run("zero-copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, Copy(fromFile, toFile, 0, 0))
+		}
+	})
+	b.Run("user space", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, Copy(fromFile, toFile, 0, 0, func(o *options) { o.noZeroCopy = true }))
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, Copy(fromFile, toFile, 0, 0, WithParallel(4)))
+		}
+	})
+}
@@ -0,0 +1,175 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// whence values of lseek looking for the next data or hole, missing in golang.org/x/sys/unix.
+	seekData = 3
+	seekHole = 4
+
+	// zeroCopyChunk is the number of bytes moved by a single system call, so progress and ctx are checked in between.
+	zeroCopyChunk = 8 * 1024 * 1024
+)
+
+// copyZero copies size bytes of source from offset to dest at its current position inside the kernel
+// with copy_file_range or sendfile, skipping the holes of source. It returns false without copying anything
+// when neither of the system calls is supported for the files.
+func copyZero(o *options, source, dest *os.File, offset, size int64, progress func(int64)) (bool, error) {
+	// copy_file_range fails with EBADF and sendfile with EINVAL for a destination opened for appending,
+	// as stdout redirected with >> is.
+	flags, err := unix.FcntlInt(dest.Fd(), unix.F_GETFL, 0)
+	if err != nil {
+		return false, err
+	}
+	if flags&unix.O_APPEND != 0 {
+		return false, nil
+	}
+
+	destOffset, err := dest.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+
+	c := &zeroCopier{source: source, dest: dest}
+	// Holes are reported once zero-copy is known to work, a fallback copies them again.
+	var holes int64
+	end := offset + size
+	for pos := offset; pos < end; {
+		if err := o.ctx.Err(); err != nil {
+			return true, err
+		}
+
+		dataStart, dataEnd, err := nextData(source, pos, end)
+		if err != nil {
+			return c.started, err
+		}
+		holes += dataStart - pos
+
+		for dataStart < dataEnd {
+			if err := o.ctx.Err(); err != nil {
+				return true, err
+			}
+			chunk := dataEnd - dataStart
+			if chunk > zeroCopyChunk {
+				chunk = zeroCopyChunk
+			}
+
+			n, err := c.copy(dataStart, destOffset+dataStart-offset, chunk)
+			if errors.Is(err, errZeroCopyUnsupported) && !c.started {
+				return false, nil
+			}
+			if err != nil {
+				return true, err
+			}
+			if n == 0 {
+				return true, io.ErrUnexpectedEOF
+			}
+			dataStart += n
+			progress(holes + n)
+			holes = 0
+		}
+		pos = dataEnd
+	}
+
+	if holes > 0 {
+		progress(holes)
+	}
+
+	// A trailing hole is not written, so the size is set explicitly.
+	destEnd := destOffset + size
+	destStat, err := dest.Stat()
+	if err != nil {
+		return true, err
+	}
+	if destStat.Size() < destEnd {
+		if err = dest.Truncate(destEnd); err != nil {
+			return true, err
+		}
+	}
+	_, err = dest.Seek(destEnd, io.SeekStart)
+	return true, err
+}
+
+// nextData returns the next range of data in file starting at pos or later and ending not after end.
+// Filesystems without holes support report the whole file as data.
+func nextData(file *os.File, pos, end int64) (int64, int64, error) {
+	start, err := file.Seek(pos, seekData)
+	if errors.Is(err, syscall.ENXIO) {
+		return end, end, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return end, end, nil
+	}
+
+	stop, err := file.Seek(start, seekHole)
+	if err != nil {
+		return 0, 0, err
+	}
+	if stop > end {
+		stop = end
+	}
+	return start, stop, nil
+}
+
+var errZeroCopyUnsupported = errors.New("zero-copy is not supported")
+
+type zeroCopier struct {
+	source, dest *os.File
+	sendfile     bool
+	started      bool
+}
+
+// copy moves up to size bytes from the source offset to the dest offset,
+// falling back from copy_file_range to sendfile on the first call.
+func (c *zeroCopier) copy(from, to, size int64) (int64, error) {
+	if !c.sendfile {
+		n, err := unix.CopyFileRange(int(c.source.Fd()), &from, int(c.dest.Fd()), &to, int(size), 0)
+		if err == nil {
+			c.started = true
+			return int64(n), nil
+		}
+		if c.started || !unsupported(err) {
+			return 0, err
+		}
+		c.sendfile = true
+	}
+
+	if _, err := c.dest.Seek(to, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := unix.Sendfile(int(c.dest.Fd()), int(c.source.Fd()), &from, int(size))
+	if err != nil {
+		if !c.started && unsupported(err) {
+			return 0, errZeroCopyUnsupported
+		}
+		return 0, err
+	}
+	c.started = true
+	return int64(n), nil
+}
+
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+func unsupported(err error) bool {
+	return errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL)
+}
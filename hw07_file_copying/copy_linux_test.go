@@ -0,0 +1,163 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopySparse(t *testing.T) {
+	const size = 8 * 1024 * 1024
+	data := bytes.Repeat([]byte("data"), 1024)
+
+	fromFile := filepath.Join(t.TempDir(), "sparse.bin")
+	f, err := os.Create(fromFile)
+	require.NoError(t, err)
+	_, err = f.WriteAt(data, 1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(size))
+	require.NoError(t, f.Close())
+
+	t.Run("holes are preserved", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		require.NoError(t, Copy(fromFile, toFile, 0, 0))
+
+		expected, err := os.ReadFile(fromFile)
+		require.NoError(t, err)
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+
+		from, to := allocated(t, fromFile), allocated(t, toFile)
+		if from >= size {
+			t.Skip("filesystem does not support holes")
+		}
+		require.Less(t, to, int64(size))
+	})
+	t.Run("offset and limit", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		require.NoError(t, Copy(fromFile, toFile, 1024*1024-100, 200))
+
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, append(make([]byte, 100), data[:100]...), actual)
+	})
+	t.Run("same as user space copy", func(t *testing.T) {
+		fast := filepath.Join(t.TempDir(), "fast.bin")
+		slow := filepath.Join(t.TempDir(), "slow.bin")
+		require.NoError(t, Copy(fromFile, fast, 1000, 2*1024*1024))
+		require.NoError(t, Copy(fromFile, slow, 1000, 2*1024*1024, func(o *options) { o.noZeroCopy = true }))
+
+		expected, err := os.ReadFile(slow)
+		require.NoError(t, err)
+		actual, err := os.ReadFile(fast)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+	t.Run("progress counts holes once", func(t *testing.T) {
+		r := &recordingReporter{}
+		require.NoError(t, Copy(fromFile, filepath.Join(t.TempDir(), "out.bin"), 100, 0, WithProgress(r)))
+		require.Equal(t, []int64{size - 100}, r.started)
+		require.Equal(t, int64(size-100), r.copied)
+	})
+}
+
+func TestCopyAppend(t *testing.T) {
+	toFile := filepath.Join(t.TempDir(), "out.txt")
+	require.NoError(t, os.WriteFile(toFile, []byte("old content\n"), 0o600))
+	out, err := os.OpenFile(toFile, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	defer out.Close()
+	withStdio(t, &os.Stdout, out)
+
+	require.NoError(t, Copy("testdata/input.txt", stdPath, 0, 10))
+
+	data, err := os.ReadFile("testdata/input.txt")
+	require.NoError(t, err)
+	actual, err := os.ReadFile(toFile)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("old content\n"), data[:10]...), actual)
+}
+
+func allocated(t *testing.T, path string) int64 {
+	t.Helper()
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	return stat.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestCopyDevice(t *testing.T) {
+	t.Run("from device", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		require.NoError(t, Copy("/dev/zero", toFile, 10, 1000))
+
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, make([]byte, 1000), actual)
+	})
+	t.Run("to device", func(t *testing.T) {
+		require.NoError(t, Copy("testdata/input.txt", "/dev/null", 0, 0))
+	})
+	t.Run("unsupported seek", func(t *testing.T) {
+		err := Copy("testdata/input.txt", "/dev/null", 0, 0, WithResume(false))
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+		err = Copy("testdata/input.txt", "/dev/null", 0, 0, WithAtomic())
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+	})
+}
+
+func TestCopyStreamMode(t *testing.T) {
+	saved := syscall.Umask(0o027)
+	defer syscall.Umask(saved)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	withStdio(t, &os.Stdin, r)
+
+	toFile := filepath.Join(t.TempDir(), "out.txt")
+	require.NoError(t, Copy(stdPath, toFile, 0, 0))
+
+	stat, err := os.Stat(toFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), stat.Mode().Perm())
+}
+
+func TestCopyFIFO(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "fifo")
+	require.NoError(t, syscall.Mkfifo(fifo, 0o600))
+
+	t.Run("interrupted open", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := Copy(fifo, filepath.Join(t.TempDir(), "out.bin"), 0, 0, WithContext(ctx))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("interrupted read", func(t *testing.T) {
+		// The writer keeps the FIFO open without writing, so reads block.
+		writer, err := os.OpenFile(fifo, os.O_RDWR, 0)
+		require.NoError(t, err)
+		defer writer.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		err = Copy(fifo, toFile, 10, 0, WithContext(ctx))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoFileExists(t, toFile)
+	})
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "os"
+
+// copyZero is not supported outside Linux, the copy goes through user space.
+func copyZero(_ *options, _, _ *os.File, _, _ int64, _ func(int64)) (bool, error) {
+	return false, nil
+}
+
+// fileOwner is not supported outside Linux, the owner is not preserved.
+func fileOwner(_ os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
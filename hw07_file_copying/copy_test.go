@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash/crc32"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,6 +38,14 @@ func TestCopy(t *testing.T) {
 		err := Copy(fromFile, "testdata/../testdata/input.txt", 0, 0)
 		require.ErrorIs(t, err, ErrFromAndToPathsEqual)
 	})
+	t.Run("to path is a link to from path", func(t *testing.T) {
+		abs, err := filepath.Abs(fromFile)
+		require.NoError(t, err)
+		link := filepath.Join(t.TempDir(), "link.txt")
+		require.NoError(t, os.Symlink(abs, link))
+		err = Copy(fromFile, link, 0, 0)
+		require.ErrorIs(t, err, ErrFromAndToPathsEqual)
+	})
 	t.Run("ok", func(t *testing.T) {
 		err := Copy(fromFile, toFile, 0, 0)
 		require.ErrorIs(t, err, nil)
@@ -101,3 +115,286 @@ func TestCopy(t *testing.T) {
 		}
 	})
 }
+
+func TestCopyResume(t *testing.T) {
+	fromFile := "testdata/input.txt"
+	ff, err := os.ReadFile(fromFile)
+	require.NoError(t, err)
+
+	t.Run("continue partial copy", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, ff[:1000], 0o600))
+
+		err := Copy(fromFile, toFile, 0, 0, WithResume(true))
+		require.NoError(t, err)
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, ff, tf)
+	})
+	t.Run("continue partial copy with offset and limit", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, ff[100:600], 0o600))
+
+		err := Copy(fromFile, toFile, 100, 1000, WithResume(true))
+		require.NoError(t, err)
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, ff[100:1100], tf)
+	})
+	t.Run("no destination", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+
+		err := Copy(fromFile, toFile, 0, 0, WithResume(false))
+		require.NoError(t, err)
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, ff, tf)
+	})
+	t.Run("destination is larger", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, ff[:1000], 0o600))
+
+		err := Copy(fromFile, toFile, 0, 10, WithResume(false))
+		require.ErrorIs(t, err, ErrDestinationMismatch)
+	})
+	t.Run("tail mismatch", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		partial := append([]byte{}, ff[:1000]...)
+		partial[999]++
+		require.NoError(t, os.WriteFile(toFile, partial, 0o600))
+
+		err := Copy(fromFile, toFile, 0, 0, WithResume(true))
+		require.ErrorIs(t, err, ErrDestinationMismatch)
+
+		err = Copy(fromFile, toFile, 0, 0, WithResume(false))
+		require.NoError(t, err)
+	})
+}
+
+func TestCopyAtomic(t *testing.T) {
+	fromFile := "testdata/input.txt"
+	ff, err := os.ReadFile(fromFile)
+	require.NoError(t, err)
+
+	t.Run("replace destination", func(t *testing.T) {
+		dir := t.TempDir()
+		toFile := filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(toFile, []byte("old content"), 0o600))
+
+		err := Copy(fromFile, toFile, 100, 1000, WithAtomic())
+		require.NoError(t, err)
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, ff[100:1100], tf)
+
+		stat, err := os.Stat(toFile)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), stat.Mode().Perm())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+	t.Run("interrupted copy keeps destination", func(t *testing.T) {
+		dir := t.TempDir()
+		toFile := filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(toFile, []byte("old content"), 0o600))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := Copy(fromFile, toFile, 0, 0, WithAtomic(), WithContext(ctx))
+		require.ErrorIs(t, err, context.Canceled)
+
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, []byte("old content"), tf)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+	t.Run("resume", func(t *testing.T) {
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithAtomic(), WithResume(false))
+		require.ErrorIs(t, err, ErrAtomicResume)
+	})
+	t.Run("by default", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, []byte("old content"), 0o600))
+
+		checksum := &Checksum{Algorithm: "crc32c", Expected: "00000000"}
+		err := Copy(fromFile, toFile, 0, 0, WithChecksum(checksum))
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, []byte("old content"), tf)
+	})
+	t.Run("in place", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, []byte("old content"), 0o600))
+		before, err := os.Stat(toFile)
+		require.NoError(t, err)
+
+		require.NoError(t, Copy(fromFile, toFile, 100, 1000, WithInPlace()))
+		tf, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, ff[100:1100], tf)
+
+		after, err := os.Stat(toFile)
+		require.NoError(t, err)
+		require.True(t, os.SameFile(before, after))
+
+		err = Copy(fromFile, toFile, 0, 0, WithAtomic(), WithInPlace())
+		require.ErrorIs(t, err, ErrAtomicInPlace)
+	})
+	t.Run("symlinked destination", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target.txt")
+		link := filepath.Join(dir, "link.txt")
+		require.NoError(t, os.WriteFile(target, []byte("old content"), 0o600))
+		require.NoError(t, os.Symlink(target, link))
+
+		require.NoError(t, Copy(fromFile, link, 100, 1000))
+		tf, err := os.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, ff[100:1100], tf)
+
+		stat, err := os.Lstat(link)
+		require.NoError(t, err)
+		require.NotZero(t, stat.Mode()&os.ModeSymlink)
+	})
+}
+
+func TestCopyChecksum(t *testing.T) {
+	fromFile := "testdata/input.txt"
+	ff, err := os.ReadFile(fromFile)
+	require.NoError(t, err)
+
+	sha := sha256.Sum256(ff[100:1100])
+	crc := crc32.Checksum(ff[100:1100], crc32.MakeTable(crc32.Castagnoli))
+	expected := map[string]string{
+		"sha256": hex.EncodeToString(sha[:]),
+		"crc32c": fmt.Sprintf("%08x", crc),
+		"xxhash": fmt.Sprintf("%016x", xxhash.Sum64(ff[100:1100])),
+	}
+
+	for algorithm, sum := range expected {
+		algorithm, sum := algorithm, sum
+		t.Run(algorithm, func(t *testing.T) {
+			checksum := &Checksum{Algorithm: algorithm, Expected: sum}
+			err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), 100, 1000, WithChecksum(checksum))
+			require.NoError(t, err)
+			require.Equal(t, sum, checksum.Sum)
+		})
+	}
+
+	t.Run("resumed copy", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, os.WriteFile(toFile, ff[100:600], 0o600))
+
+		checksum := &Checksum{Algorithm: "sha256"}
+		err := Copy(fromFile, toFile, 100, 1000, WithResume(false), WithChecksum(checksum))
+		require.NoError(t, err)
+		require.Equal(t, expected["sha256"], checksum.Sum)
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		checksum := &Checksum{Algorithm: "crc32c", Expected: "00000000"}
+		err := Copy(fromFile, toFile, 100, 1000, WithChecksum(checksum), WithAtomic())
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+		require.NoFileExists(t, toFile)
+	})
+	t.Run("unknown hash", func(t *testing.T) {
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithChecksum(&Checksum{Algorithm: "md4"}))
+		require.ErrorIs(t, err, ErrUnknownHash)
+	})
+}
+
+// withStdio replaces stdin or stdout with the given file for the test.
+func withStdio(t *testing.T, std **os.File, file *os.File) {
+	t.Helper()
+	saved := *std
+	*std = file
+	t.Cleanup(func() {
+		*std = saved
+	})
+}
+
+func TestCopyStream(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+
+	stdin := func(t *testing.T) {
+		t.Helper()
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		t.Cleanup(func() {
+			_ = r.Close()
+		})
+		withStdio(t, &os.Stdin, r)
+	}
+
+	t.Run("from stdin", func(t *testing.T) {
+		for _, tc := range []struct {
+			offset, limit int64
+			expected      []byte
+		}{
+			{offset: 0, limit: 0, expected: data},
+			{offset: 5, limit: 0, expected: data[5:]},
+			{offset: 5, limit: 10, expected: data[5:15]},
+			{offset: 15, limit: 100, expected: data[15:]},
+			{offset: 20, limit: 0, expected: []byte{}},
+		} {
+			tc := tc
+			t.Run(fmt.Sprintf("offset %d limit %d", tc.offset, tc.limit), func(t *testing.T) {
+				stdin(t)
+				toFile := filepath.Join(t.TempDir(), "out.txt")
+				require.NoError(t, Copy(stdPath, toFile, tc.offset, tc.limit))
+
+				actual, err := os.ReadFile(toFile)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, actual)
+			})
+		}
+	})
+	t.Run("offset exceeds stream", func(t *testing.T) {
+		stdin(t)
+		err := Copy(stdPath, filepath.Join(t.TempDir(), "out.txt"), 21, 0)
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+	})
+	t.Run("atomic with checksum", func(t *testing.T) {
+		stdin(t)
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		checksum := &Checksum{Algorithm: "xxhash", Expected: fmt.Sprintf("%016x", xxhash.Sum64(data[2:]))}
+		require.NoError(t, Copy(stdPath, toFile, 2, 0, WithAtomic(), WithChecksum(checksum)))
+
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, data[2:], actual)
+	})
+	t.Run("resume", func(t *testing.T) {
+		stdin(t)
+		err := Copy(stdPath, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithResume(false))
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+	})
+	t.Run("to stdout", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		withStdio(t, &os.Stdout, w)
+
+		fromFile := filepath.Join(t.TempDir(), "input.txt")
+		require.NoError(t, os.WriteFile(fromFile, data, 0o600))
+		require.NoError(t, Copy(fromFile, stdPath, 3, 4))
+		require.NoError(t, w.Close())
+
+		actual := make([]byte, len(data))
+		n, err := r.Read(actual)
+		require.NoError(t, err)
+		require.Equal(t, data[3:7], actual[:n])
+
+		err = Copy(fromFile, stdPath, 0, 0, WithAtomic())
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+	})
+}
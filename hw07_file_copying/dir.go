@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrUnknownSymlinkPolicy = errors.New("unknown symlink policy")
+	ErrSymlinkLoop          = errors.New("symlink loop")
+	ErrDirChecksum          = errors.New("checksum is not supported for directories")
+	ErrDirRange             = errors.New("range, offset and limit are not supported for directories")
+)
+
+// SymlinkPolicy defines how CopyDir handles symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkCopy creates the same link in the destination.
+	SymlinkCopy SymlinkPolicy = iota
+	// SymlinkFollow copies the file or the directory the link points to.
+	SymlinkFollow
+	// SymlinkSkip ignores links.
+	SymlinkSkip
+)
+
+// ParseSymlinkPolicy returns the policy named follow, copy or skip.
+func ParseSymlinkPolicy(name string) (SymlinkPolicy, error) {
+	switch strings.ToLower(name) {
+	case "copy":
+		return SymlinkCopy, nil
+	case "follow":
+		return SymlinkFollow, nil
+	case "skip":
+		return SymlinkSkip, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownSymlinkPolicy, name)
+	}
+}
+
+// WithInclude copies only the files matching one of the glob patterns.
+// A pattern is matched against the path relative to the copied directory and against the file name.
+func WithInclude(patterns ...string) Option {
+	return func(o *options) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude skips the files and directories matching one of the glob patterns.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithSymlinks sets the symlink policy of CopyDir, links are copied as links by default.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(o *options) {
+		o.symlinks = policy
+	}
+}
+
+// WithPreserve keeps the mode, the modification time and, when permitted, the owner of the copied entries.
+func WithPreserve() Option {
+	return func(o *options) {
+		o.preserve = true
+	}
+}
+
+// WithDryRun writes the entries CopyDir would copy to w instead of copying them.
+func WithDryRun(w io.Writer) Option {
+	return func(o *options) {
+		o.dryRun = w
+	}
+}
+
+// CopyDir copies the contents of the fromDir directory into toDir recursively, creating toDir if needed.
+// Files are copied whole with the file options of Copy, checksums and ranges are not supported.
+func CopyDir(fromDir, toDir string, opts ...Option) error {
+	if err := validateFilePaths(fromDir, toDir); err != nil {
+		return err
+	}
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+	if o.checksum != nil {
+		return ErrDirChecksum
+	}
+	if o.rng != nil {
+		return ErrDirRange
+	}
+	if err = validatePatterns(o.include); err != nil {
+		return err
+	}
+	if err = validatePatterns(o.exclude); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(fromDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: %w", fromDir, os.ErrInvalid)
+	}
+
+	c := &dirCopier{opts: o}
+	return c.copyDir(fromDir, toDir, "", info)
+}
+
+type dirCopier struct {
+	opts *options
+	// parents are the directories being copied, used to detect loops of followed links.
+	parents []os.FileInfo
+}
+
+func (c *dirCopier) copyDir(from, to, rel string, info os.FileInfo) error {
+	for _, parent := range c.parents {
+		if os.SameFile(parent, info) {
+			return fmt.Errorf("%w: %s", ErrSymlinkLoop, from)
+		}
+	}
+	c.parents = append(c.parents, info)
+	defer func() {
+		c.parents = c.parents[:len(c.parents)-1]
+	}()
+
+	if err := c.mkdir(from, to, info); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = c.opts.ctx.Err(); err != nil {
+			return err
+		}
+		entryRel := filepath.Join(rel, entry.Name())
+		if matchAny(c.opts.exclude, entryRel) {
+			continue
+		}
+		if err = c.copyEntry(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name()), entryRel); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.dryRun == nil && c.opts.preserve {
+		return preserveAttrs(to, info)
+	}
+	return nil
+}
+
+func (c *dirCopier) copyEntry(from, to, rel string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch c.opts.symlinks {
+		case SymlinkSkip:
+			return nil
+		case SymlinkCopy:
+			if !c.included(info, rel) {
+				return nil
+			}
+			return c.symlink(from, to, info)
+		case SymlinkFollow:
+			if info, err = os.Stat(from); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		return c.copyDir(from, to, rel, info)
+	case !c.included(info, rel):
+		return nil
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("%s: %w", from, os.ErrInvalid)
+	}
+
+	if c.opts.dryRun != nil {
+		_, err = fmt.Fprintf(c.opts.dryRun, "%s -> %s\n", from, to)
+		return err
+	}
+	if err = copyFile(c.opts, from, to, 0, 0); err != nil {
+		return err
+	}
+	if c.opts.preserve {
+		return preserveAttrs(to, info)
+	}
+	return nil
+}
+
+func (c *dirCopier) mkdir(from, to string, info os.FileInfo) error {
+	if c.opts.dryRun != nil {
+		_, err := fmt.Fprintf(c.opts.dryRun, "%s/ -> %s/\n", from, to)
+		return err
+	}
+	// The directory stays writable until its contents are copied, preserve sets the mode afterwards.
+	err := os.Mkdir(to, info.Mode().Perm()|0o700)
+	if errors.Is(err, os.ErrExist) {
+		if toInfo, statErr := os.Stat(to); statErr == nil && toInfo.IsDir() {
+			return nil
+		}
+	}
+	return err
+}
+
+func (c *dirCopier) symlink(from, to string, info os.FileInfo) error {
+	target, err := os.Readlink(from)
+	if err != nil {
+		return err
+	}
+	if c.opts.dryRun != nil {
+		_, err = fmt.Fprintf(c.opts.dryRun, "%s -> %s (link to %s)\n", from, to, target)
+		return err
+	}
+
+	if err = os.Remove(to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = os.Symlink(target, to); err != nil {
+		return err
+	}
+	if c.opts.preserve {
+		return chown(to, info)
+	}
+	return nil
+}
+
+// included reports whether the file matches the include patterns, directories are always included.
+func (c *dirCopier) included(info os.FileInfo, rel string) bool {
+	return info.IsDir() || len(c.opts.include) == 0 || matchAny(c.opts.include, rel)
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %s", err, pattern)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func preserveAttrs(path string, info os.FileInfo) error {
+	if err := chown(path, info); err != nil {
+		return err
+	}
+	if err := os.Chmod(path, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// chown sets the owner of info to path, lack of permissions to do it is not an error.
+func chown(path string, info os.FileInfo) error {
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return nil
+	}
+	if err := os.Lchown(path, uid, gid); err != nil && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	return nil
+}
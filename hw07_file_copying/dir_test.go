@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func makeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":         "a",
+		"b.log":         "b",
+		"sub/c.txt":     "c",
+		"sub/deep/d.go": "d",
+		"tmp/e.txt":     "e",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(root, "link.txt")))
+	require.NoError(t, os.Symlink("sub", filepath.Join(root, "sublink")))
+	return root
+}
+
+func listTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		require.NoError(t, err)
+		rel, err := filepath.Rel(root, path)
+		require.NoError(t, err)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			require.NoError(t, err)
+			tree[rel] = "-> " + target
+		case info.Mode().IsRegular():
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			tree[rel] = string(content)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return tree
+}
+
+func TestCopyDir(t *testing.T) {
+	root := makeTree(t)
+
+	t.Run("copy links", func(t *testing.T) {
+		to := filepath.Join(t.TempDir(), "out")
+		require.NoError(t, CopyDir(root, to))
+		require.Equal(t, map[string]string{
+			"a.txt":         "a",
+			"b.log":         "b",
+			"sub/c.txt":     "c",
+			"sub/deep/d.go": "d",
+			"tmp/e.txt":     "e",
+			"link.txt":      "-> a.txt",
+			"sublink":       "-> sub",
+		}, listTree(t, to))
+	})
+	t.Run("follow links", func(t *testing.T) {
+		to := t.TempDir()
+		require.NoError(t, CopyDir(root, to, WithSymlinks(SymlinkFollow), WithExclude("tmp")))
+		require.Equal(t, map[string]string{
+			"a.txt":             "a",
+			"b.log":             "b",
+			"sub/c.txt":         "c",
+			"sub/deep/d.go":     "d",
+			"link.txt":          "a",
+			"sublink/c.txt":     "c",
+			"sublink/deep/d.go": "d",
+		}, listTree(t, to))
+	})
+	t.Run("skip links and filter", func(t *testing.T) {
+		to := t.TempDir()
+		err := CopyDir(root, to, WithSymlinks(SymlinkSkip), WithInclude("*.txt"), WithExclude("tmp", "sub/*.txt"))
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"a.txt": "a"}, listTree(t, to))
+	})
+	t.Run("dry run", func(t *testing.T) {
+		to := filepath.Join(t.TempDir(), "out")
+		out := &bytes.Buffer{}
+		require.NoError(t, CopyDir(root, to, WithInclude("d.go"), WithDryRun(out)))
+		require.NoDirExists(t, to)
+		require.Contains(t, out.String(), filepath.Join(root, "sub/deep/d.go")+" -> "+filepath.Join(to, "sub/deep/d.go"))
+		require.NotContains(t, out.String(), "a.txt")
+	})
+	t.Run("preserve", func(t *testing.T) {
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		from := t.TempDir()
+		file := filepath.Join(from, "f.txt")
+		require.NoError(t, os.WriteFile(file, []byte("f"), 0o600))
+		require.NoError(t, os.Chmod(file, 0o640))
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+
+		to := t.TempDir()
+		require.NoError(t, CopyDir(from, to, WithPreserve()))
+		info, err := os.Stat(filepath.Join(to, "f.txt"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		require.True(t, mtime.Equal(info.ModTime()))
+	})
+	t.Run("link loop", func(t *testing.T) {
+		from := t.TempDir()
+		require.NoError(t, os.Symlink(".", filepath.Join(from, "self")))
+		err := CopyDir(from, t.TempDir(), WithSymlinks(SymlinkFollow))
+		require.ErrorIs(t, err, ErrSymlinkLoop)
+	})
+	t.Run("into itself", func(t *testing.T) {
+		err := CopyDir(root, filepath.Join(root, "sub", "copy"))
+		require.ErrorIs(t, err, ErrCopyIntoItself)
+	})
+	t.Run("into itself through a link", func(t *testing.T) {
+		link := filepath.Join(t.TempDir(), "link")
+		require.NoError(t, os.Symlink(filepath.Join(root, "sub"), link))
+		err := CopyDir(root, filepath.Join(link, "copy"))
+		require.ErrorIs(t, err, ErrCopyIntoItself)
+	})
+	t.Run("bad pattern", func(t *testing.T) {
+		err := CopyDir(root, t.TempDir(), WithInclude("["))
+		require.ErrorIs(t, err, filepath.ErrBadPattern)
+	})
+	t.Run("checksum", func(t *testing.T) {
+		err := CopyDir(root, t.TempDir(), WithChecksum(&Checksum{Algorithm: "sha256"}))
+		require.ErrorIs(t, err, ErrDirChecksum)
+	})
+	t.Run("range", func(t *testing.T) {
+		err := CopyDir(root, t.TempDir(), WithRange(Range{Start: 10}))
+		require.ErrorIs(t, err, ErrDirRange)
+	})
+	t.Run("unknown symlink policy", func(t *testing.T) {
+		_, err := ParseSymlinkPolicy("hard")
+		require.ErrorIs(t, err, ErrUnknownSymlinkPolicy)
+	})
+}
@@ -3,14 +3,15 @@ module github.com/DaryaPe/hw-test/hw07_file_copying
 go 1.19
 
 require (
+	github.com/cespare/xxhash/v2 v2.1.2
 	github.com/cheggaaa/pb v1.0.29
 	github.com/stretchr/testify v1.8.2
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/mattn/go-runewidth v0.0.4 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,26 +1,131 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
 )
 
 var (
-	from, to      string
-	limit, offset int64
+	from, to          string
+	limit, offset     int64
+	resume, checkTail bool
+	atomic, inPlace   bool
+	verify, expect    string
+	byteRange         string
+	parallel          int
+	progress          string
+
+	recursive, preserve, dryRun bool
+	include, exclude, symlinks  string
 )
 
 func init() {
-	flag.StringVar(&from, "from", "", "file to read from")
-	flag.StringVar(&to, "to", "", "file to write to")
+	flag.StringVar(&from, "from", "", "file to read from, - for stdin")
+	flag.StringVar(&to, "to", "", "file to write to, - for stdout")
 	flag.Int64Var(&limit, "limit", 0, "limit of bytes to copy")
-	flag.Int64Var(&offset, "offset", 0, "offset in input file")
+	flag.Int64Var(&offset, "offset", 0, "offset in input file, negative is relative to its end")
+	flag.StringVar(&byteRange, "range", "", "start:end range of input file with K, M or G suffixes")
+	flag.BoolVar(&resume, "resume", false, "continue an interrupted copy from the end of the output file")
+	flag.BoolVar(&checkTail, "check-tail", false, "compare the last copied block with the input file on resume")
+	flag.BoolVar(&atomic, "atomic", false, "fail if the output file can not be replaced by a renamed temporary file")
+	flag.BoolVar(&inPlace, "in-place", false, "write into the output file instead of a temporary file renamed to it")
+	flag.IntVar(&parallel, "parallel", 1, "number of workers copying chunks of input file at once")
+	flag.StringVar(&progress, "progress", "bar", "progress reporting: bar, log, json or none")
+	flag.StringVar(&verify, "verify", "", "hash of the copied bytes to print: sha256, crc32c or xxhash")
+	flag.StringVar(&expect, "expect", "", "expected hex digest of the copied bytes for -verify")
+	flag.BoolVar(&recursive, "recursive", false, "copy the contents of the input directory into the output directory")
+	flag.StringVar(&include, "include", "", "comma-separated glob patterns of files to copy with -recursive")
+	flag.StringVar(&exclude, "exclude", "", "comma-separated glob patterns of entries to skip with -recursive")
+	flag.StringVar(&symlinks, "symlinks", "copy", "symlinks handling with -recursive: follow, copy or skip")
+	flag.BoolVar(&preserve, "preserve", false, "keep mode, modification time and owner with -recursive")
+	flag.BoolVar(&dryRun, "dry-run", false, "list what -recursive would copy without copying")
 }
 
 func main() {
 	flag.Parse()
-	err := Copy(from, to, offset, limit)
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Copy error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// The first interrupt cancels the copy to clean up, the next one kills the process.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// The copy written to stdout must not be mixed with the progress and the digest.
+	infoOut := os.Stdout
+	if to == stdPath {
+		infoOut = os.Stderr
+	}
+	reporter, err := NewReporter(progress, infoOut)
+	if err != nil {
+		return err
+	}
+
+	opts := []Option{WithContext(ctx), WithParallel(parallel), WithProgress(reporter)}
+	if atomic {
+		opts = append(opts, WithAtomic())
+	}
+	if inPlace {
+		opts = append(opts, WithInPlace())
+	}
+	if resume {
+		opts = append(opts, WithResume(checkTail))
+	}
+	if byteRange != "" {
+		r, err := ParseRange(byteRange)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithRange(r))
+	}
+	var checksum *Checksum
+	if verify != "" {
+		checksum = &Checksum{Algorithm: verify, Expected: expect}
+		opts = append(opts, WithChecksum(checksum))
+	}
+	if recursive {
+		err = copyDir(opts)
+	} else {
+		err = Copy(from, to, offset, limit, opts...)
+	}
+	if checksum != nil && checksum.Sum != "" {
+		fmt.Fprintf(infoOut, "%s: %s\n", checksum.Algorithm, checksum.Sum)
+	}
+	return err
+}
+
+func copyDir(opts []Option) error {
+	if offset != 0 || limit != 0 {
+		return ErrDirRange
+	}
+	policy, err := ParseSymlinkPolicy(symlinks)
 	if err != nil {
-		fmt.Printf("Copy error: %v/n", err)
+		return err
+	}
+	opts = append(opts, WithSymlinks(policy))
+	if include != "" {
+		opts = append(opts, WithInclude(strings.Split(include, ",")...))
+	}
+	if exclude != "" {
+		opts = append(opts, WithExclude(strings.Split(exclude, ",")...))
+	}
+	if preserve {
+		opts = append(opts, WithPreserve())
+	}
+	if dryRun {
+		opts = append(opts, WithDryRun(os.Stdout))
 	}
+	return CopyDir(from, to, opts...)
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var ErrWrongParallelism = errors.New("wrong number of parallel workers")
+
+const (
+	// parallelChunkSize is the size of a range copied by a single worker at once.
+	parallelChunkSize = 8 * 1024 * 1024
+	// parallelBufferSize is the size of the buffer of a worker.
+	parallelBufferSize = 256 * 1024
+)
+
+// WithParallel copies the file in chunks by n workers at once, 0 and 1 mean a sequential copy.
+// Both the source and the destination must be regular files.
+func WithParallel(n int) Option {
+	return func(o *options) {
+		o.parallel = n
+	}
+}
+
+// execParallel copies copySize bytes of source from offset to dest at its current position by o.parallel workers.
+func execParallel(o *options, source, dest *os.File, offset, copySize int64, progress func(int64)) error {
+	if !regular(source) || !regular(dest) {
+		return fmt.Errorf("%w: parallel copy", ErrUnsupportedSeek)
+	}
+	destOffset, err := dest.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(o.ctx)
+	defer cancel()
+
+	chunks := (copySize + parallelChunkSize - 1) / parallelChunkSize
+	chunksCh := make(chan int64, chunks)
+	for chunk := int64(0); chunk < chunks; chunk++ {
+		chunksCh <- chunk
+	}
+	close(chunksCh)
+
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	wg.Add(o.parallel)
+	for i := 0; i < o.parallel; i++ {
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, parallelBufferSize)
+			for chunk := range chunksCh {
+				start := chunk * parallelChunkSize
+				size := copySize - start
+				if size > parallelChunkSize {
+					size = parallelChunkSize
+				}
+				err := copyChunk(ctx, buf, source, dest, offset+start, destOffset+start, size, progress)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// Chunks are written out of order, so the checksum is computed from the written destination.
+	if o.hash != nil {
+		if _, err = io.Copy(o.hash, io.NewSectionReader(dest, destOffset, copySize)); err != nil {
+			return err
+		}
+	}
+	_, err = dest.Seek(destOffset+copySize, io.SeekStart)
+	return err
+}
+
+func copyChunk(
+	ctx context.Context,
+	buf []byte,
+	source, dest *os.File,
+	from, to, size int64,
+	progress func(int64),
+) error {
+	for size > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if int64(len(buf)) > size {
+			buf = buf[:size]
+		}
+
+		n, err := source.ReadAt(buf, from)
+		if errors.Is(err, io.EOF) && n == len(buf) {
+			err = nil
+		}
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = dest.WriteAt(buf[:n], to); err != nil {
+			return err
+		}
+
+		from += int64(n)
+		to += int64(n)
+		size -= int64(n)
+		progress(int64(n))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyParallel(t *testing.T) {
+	data := make([]byte, 3*parallelChunkSize+12345)
+	rand.Read(data) //nolint:gosec
+	fromFile := filepath.Join(t.TempDir(), "input.bin")
+	require.NoError(t, os.WriteFile(fromFile, data, 0o600))
+
+	for _, tc := range []struct {
+		name          string
+		offset, limit int64
+		expected      []byte
+	}{
+		{name: "whole file", expected: data},
+		{name: "offset", offset: 1000, expected: data[1000:]},
+		{name: "offset and limit", offset: 1000, limit: parallelChunkSize + 1, expected: data[1000 : parallelChunkSize+1001]},
+		{name: "small", offset: 10, limit: 10, expected: data[10:20]},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			toFile := filepath.Join(t.TempDir(), "out.bin")
+			require.NoError(t, Copy(fromFile, toFile, tc.offset, tc.limit, WithParallel(4)))
+
+			actual, err := os.ReadFile(toFile)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("resume with checksum", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		require.NoError(t, os.WriteFile(toFile, data[:parallelChunkSize/2], 0o600))
+
+		sum := sha256.Sum256(data)
+		checksum := &Checksum{Algorithm: "sha256", Expected: hex.EncodeToString(sum[:])}
+		require.NoError(t, Copy(fromFile, toFile, 0, 0, WithParallel(3), WithResume(true), WithChecksum(checksum)))
+
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, data, actual)
+	})
+	t.Run("atomic", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.bin")
+		require.NoError(t, Copy(fromFile, toFile, 0, 0, WithParallel(2), WithAtomic()))
+
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, data, actual)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.bin"), 0, 0, WithParallel(2), WithContext(ctx))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("stream", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+		defer w.Close()
+		withStdio(t, &os.Stdin, r)
+
+		err = Copy(stdPath, filepath.Join(t.TempDir(), "out.bin"), 0, 0, WithParallel(2))
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+	})
+	t.Run("wrong parallelism", func(t *testing.T) {
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.bin"), 0, 0, WithParallel(-1))
+		require.ErrorIs(t, err, ErrWrongParallelism)
+	})
+}
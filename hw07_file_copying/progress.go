@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb"
+)
+
+var ErrUnknownReporter = errors.New("unknown progress reporter")
+
+// ProgressReporter receives the progress of copying a file.
+// Copying a directory starts and finishes it once per file.
+type ProgressReporter interface {
+	// Start is called before copying total bytes, 0 means an unknown size.
+	Start(total int64)
+	// Add is called with the number of copied bytes, possibly from several goroutines.
+	Add(n int64)
+	// Finish is called with the result once the copy is done.
+	Finish(err error)
+}
+
+// WithProgress reports the progress of the copy to r, nothing is reported by default.
+func WithProgress(r ProgressReporter) Option {
+	return func(o *options) {
+		o.progress = r
+	}
+}
+
+// NewReporter returns the reporter named bar, log, json or none writing to w.
+func NewReporter(name string, w io.Writer) (ProgressReporter, error) {
+	switch name {
+	case "bar":
+		return NewBarReporter(w), nil
+	case "log":
+		return NewLogReporter(log.New(w, "", log.LstdFlags), time.Second), nil
+	case "json":
+		return NewJSONReporter(w, time.Second), nil
+	case "none":
+		return SilentReporter{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownReporter, name)
+	}
+}
+
+// SilentReporter reports nothing.
+type SilentReporter struct{}
+
+func (SilentReporter) Start(int64) {}
+
+func (SilentReporter) Add(int64) {}
+
+func (SilentReporter) Finish(error) {}
+
+// BarReporter draws a progress bar on a terminal.
+type BarReporter struct {
+	w   io.Writer
+	bar *pb.ProgressBar
+}
+
+func NewBarReporter(w io.Writer) *BarReporter {
+	return &BarReporter{w: w}
+}
+
+func (r *BarReporter) Start(total int64) {
+	r.bar = pb.New64(total).SetUnits(pb.U_BYTES).SetRefreshRate(100 * time.Millisecond)
+	r.bar.ShowSpeed = true
+	r.bar.ShowBar = total > 0
+	r.bar.Output = r.w
+	r.bar.Start()
+}
+
+func (r *BarReporter) Add(n int64) {
+	r.bar.Add64(n)
+}
+
+func (r *BarReporter) Finish(error) {
+	r.bar.Finish()
+}
+
+// LogReporter writes a log line with the copied bytes every interval.
+type LogReporter struct {
+	logger *log.Logger
+	ticker *ticker
+}
+
+func NewLogReporter(logger *log.Logger, interval time.Duration) *LogReporter {
+	r := &LogReporter{logger: logger}
+	r.ticker = newTicker(interval, r.report)
+	return r
+}
+
+func (r *LogReporter) Start(total int64) {
+	if total > 0 {
+		r.logger.Printf("copying %d bytes", total)
+	} else {
+		r.logger.Print("copying until EOF")
+	}
+	r.ticker.start(total)
+}
+
+func (r *LogReporter) Add(n int64) {
+	r.ticker.add(n)
+}
+
+func (r *LogReporter) Finish(err error) {
+	copied, _ := r.ticker.stop()
+	if err != nil {
+		r.logger.Printf("copy failed after %d bytes: %v", copied, err)
+		return
+	}
+	r.logger.Printf("copied %d bytes", copied)
+}
+
+func (r *LogReporter) report(copied, total int64) {
+	if total > 0 {
+		r.logger.Printf("copied %d of %d bytes (%d%%)", copied, total, copied*100/total)
+		return
+	}
+	r.logger.Printf("copied %d bytes", copied)
+}
+
+// ProgressEvent is written by JSONReporter as a line of JSON.
+type ProgressEvent struct {
+	// Event is one of start, progress and finish.
+	Event  string `json:"event"`
+	Copied int64  `json:"copied"`
+	// Total is 0 for an unknown size.
+	Total int64  `json:"total"`
+	Error string `json:"error,omitempty"`
+}
+
+// JSONReporter writes a ProgressEvent on start, on finish and every interval in between.
+type JSONReporter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	ticker *ticker
+}
+
+func NewJSONReporter(w io.Writer, interval time.Duration) *JSONReporter {
+	r := &JSONReporter{enc: json.NewEncoder(w)}
+	r.ticker = newTicker(interval, func(copied, total int64) {
+		r.write(ProgressEvent{Event: "progress", Copied: copied, Total: total})
+	})
+	return r
+}
+
+func (r *JSONReporter) Start(total int64) {
+	r.write(ProgressEvent{Event: "start", Total: total})
+	r.ticker.start(total)
+}
+
+func (r *JSONReporter) Add(n int64) {
+	r.ticker.add(n)
+}
+
+func (r *JSONReporter) Finish(err error) {
+	copied, total := r.ticker.stop()
+	event := ProgressEvent{Event: "finish", Copied: copied, Total: total}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.write(event)
+}
+
+func (r *JSONReporter) write(event ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(event)
+}
+
+// ticker counts the copied bytes and calls report with them every interval between start and stop.
+type ticker struct {
+	interval time.Duration
+	report   func(copied, total int64)
+
+	mu            sync.Mutex
+	copied, total int64
+	quit, done    chan struct{}
+}
+
+func newTicker(interval time.Duration, report func(copied, total int64)) *ticker {
+	return &ticker{interval: interval, report: report}
+}
+
+func (t *ticker) start(total int64) {
+	t.mu.Lock()
+	t.copied, t.total = 0, total
+	t.mu.Unlock()
+
+	t.quit, t.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(t.done)
+		tick := time.NewTicker(t.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-t.quit:
+				return
+			case <-tick.C:
+				t.report(t.get())
+			}
+		}
+	}()
+}
+
+func (t *ticker) add(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.copied += n
+}
+
+func (t *ticker) get() (int64, int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.copied, t.total
+}
+
+// stop stops reporting and returns the copied and the total bytes.
+func (t *ticker) stop() (int64, int64) {
+	close(t.quit)
+	<-t.done
+	return t.get()
+}
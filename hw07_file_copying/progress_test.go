@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingReporter struct {
+	mu       sync.Mutex
+	started  []int64
+	copied   int64
+	finished []error
+	// onStart is called when the copy starts.
+	onStart func()
+}
+
+func (r *recordingReporter) Start(total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, total)
+	if r.onStart != nil {
+		r.onStart()
+	}
+}
+
+func (r *recordingReporter) Add(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.copied += n
+}
+
+func (r *recordingReporter) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, err)
+}
+
+func TestCopyProgress(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	fromFile := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(fromFile, data, 0o600))
+
+	for name, opts := range map[string][]Option{
+		"sequential": {func(o *options) { o.noZeroCopy = true }},
+		"zero-copy":  nil,
+		"parallel":   {WithParallel(3)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			r := &recordingReporter{}
+			err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), 100, 5000, append(opts, WithProgress(r))...)
+			require.NoError(t, err)
+			require.Equal(t, []int64{5000}, r.started)
+			require.Equal(t, int64(5000), r.copied)
+			require.Equal(t, []error{nil}, r.finished)
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+		go func() {
+			_, _ = w.Write(data)
+			_ = w.Close()
+		}()
+		withStdio(t, &os.Stdin, r)
+
+		reporter := &recordingReporter{}
+		require.NoError(t, Copy(stdPath, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithProgress(reporter)))
+		require.Equal(t, []int64{0}, reporter.started)
+		require.Equal(t, int64(len(data)), reporter.copied)
+	})
+	t.Run("failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := &recordingReporter{onStart: cancel}
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithContext(ctx), WithProgress(r),
+			func(o *options) { o.noZeroCopy = true })
+		require.ErrorIs(t, err, context.Canceled)
+		require.Len(t, r.finished, 1)
+		require.ErrorIs(t, r.finished[0], context.Canceled)
+	})
+}
+
+func TestReporters(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		r := NewJSONReporter(out, time.Millisecond)
+		r.Start(100)
+		r.Add(40)
+		time.Sleep(10 * time.Millisecond)
+		r.Add(60)
+		r.Finish(nil)
+
+		var events []ProgressEvent
+		dec := json.NewDecoder(out)
+		for dec.More() {
+			var event ProgressEvent
+			require.NoError(t, dec.Decode(&event))
+			events = append(events, event)
+		}
+		require.Greater(t, len(events), 2)
+		require.Equal(t, ProgressEvent{Event: "start", Total: 100}, events[0])
+		require.Contains(t, events, ProgressEvent{Event: "progress", Copied: 40, Total: 100})
+		require.Equal(t, ProgressEvent{Event: "finish", Copied: 100, Total: 100}, events[len(events)-1])
+	})
+	t.Run("log", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		r := NewLogReporter(log.New(out, "", 0), time.Hour)
+		r.Start(0)
+		r.Add(10)
+		r.Finish(context.Canceled)
+		require.Equal(t, "copying until EOF\ncopy failed after 10 bytes: context canceled\n", out.String())
+	})
+	t.Run("bar", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		r := NewBarReporter(out)
+		r.Start(100)
+		r.Add(100)
+		r.Finish(nil)
+		require.Contains(t, out.String(), "100.00%")
+	})
+	t.Run("by name", func(t *testing.T) {
+		for _, name := range []string{"bar", "log", "json", "none"} {
+			_, err := NewReporter(name, &bytes.Buffer{})
+			require.NoError(t, err)
+		}
+		_, err := NewReporter("xml", &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUnknownReporter)
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+// Range is the [Start, End) range of the source to copy.
+// Negative values are relative to the end of the file, an End of 0 means the end of the file.
+type Range struct {
+	Start, End int64
+}
+
+// WithRange copies the range of the source instead of the offset and limit passed to Copy.
+func WithRange(r Range) Option {
+	return func(o *options) {
+		o.rng = &r
+	}
+}
+
+// ParseRange parses the start:end range, both bounds are optional and may have a K, M or G suffix,
+// e.g. "-10M:" is the last 10 megabytes and "1K:2K" is the second kilobyte.
+func ParseRange(s string) (Range, error) {
+	startStr, endStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return Range{}, fmt.Errorf("%w: %q has no colon", ErrInvalidRange, s)
+	}
+
+	var r Range
+	var err error
+	if r.Start, err = parseSize(startStr); err != nil {
+		return Range{}, fmt.Errorf("%w: %q has a wrong start", ErrInvalidRange, s)
+	}
+	if r.End, err = parseSize(endStr); err != nil {
+		return Range{}, fmt.Errorf("%w: %q has a wrong end", ErrInvalidRange, s)
+	}
+	if r.End != 0 && (r.Start >= 0) == (r.End > 0) && r.End <= r.Start {
+		return Range{}, fmt.Errorf("%w: %q ends before it starts", ErrInvalidRange, s)
+	}
+	return r, nil
+}
+
+// parseSize parses a number of bytes with an optional K, M or G binary suffix, an empty string is 0.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt64/multiplier || n < math.MinInt64/multiplier {
+		return 0, strconv.ErrRange
+	}
+	return n * multiplier, nil
+}
+
+// span returns the offset and the number of bytes to copy from a file of the given size.
+func (o *options) span(offset, limit, size int64) (int64, int64, error) {
+	if o.rng == nil {
+		if offset < 0 {
+			offset += size
+		}
+		if offset < 0 || offset > size {
+			return 0, 0, ErrOffsetExceedsFileSize
+		}
+		return offset, calcCopySize(offset, limit, size), nil
+	}
+
+	start, end := o.rng.Start, o.rng.End
+	if start < 0 {
+		start += size
+	}
+	if start < 0 || start > size {
+		return 0, 0, ErrOffsetExceedsFileSize
+	}
+	if end <= 0 {
+		end += size
+	}
+	if end > size {
+		end = size
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("%w: %d:%d of %d bytes ends before it starts", ErrInvalidRange, o.rng.Start, o.rng.End, size)
+	}
+	return start, end - start, nil
+}
+
+// streamSpan returns the offset and the limit to copy from a source of unknown size.
+func (o *options) streamSpan(offset, limit int64) (int64, int64, error) {
+	if o.rng != nil {
+		if o.rng.End < 0 {
+			return 0, 0, fmt.Errorf("%w: range relative to the end of a stream", ErrUnsupportedSeek)
+		}
+		offset, limit = o.rng.Start, 0
+		if o.rng.End > 0 {
+			limit = o.rng.End - o.rng.Start
+		}
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("%w: offset relative to the end of a stream", ErrUnsupportedSeek)
+	}
+	return offset, limit, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected Range
+	}{
+		{input: ":", expected: Range{}},
+		{input: "10:", expected: Range{Start: 10}},
+		{input: ":20", expected: Range{End: 20}},
+		{input: "1K:2k", expected: Range{Start: 1024, End: 2048}},
+		{input: "-10M:", expected: Range{Start: -10 << 20}},
+		{input: "1G:-1M", expected: Range{Start: 1 << 30, End: -1 << 20}},
+		{input: "-2K:-1K", expected: Range{Start: -2048, End: -1024}},
+	} {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			r, err := ParseRange(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, r)
+		})
+	}
+
+	for _, input := range []string{
+		"", "10", "1T:", "a:b", "K:", "5:5", "10:5", "-1K:-2K", "9007199254740992K:", ":-9007199254740993K",
+	} {
+		input := input
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := ParseRange(input)
+			require.ErrorIs(t, err, ErrInvalidRange)
+		})
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	fromFile := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(fromFile, data, 0o600))
+
+	copied := func(t *testing.T, offset, limit int64, opts ...Option) []byte {
+		t.Helper()
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, Copy(fromFile, toFile, offset, limit, opts...))
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		return actual
+	}
+
+	t.Run("negative offset", func(t *testing.T) {
+		require.Equal(t, data[15:], copied(t, -5, 0))
+		require.Equal(t, data[10:12], copied(t, -10, 2))
+		require.Equal(t, data, copied(t, -20, 0))
+	})
+	t.Run("negative offset exceeds file size", func(t *testing.T) {
+		err := Copy(fromFile, filepath.Join(t.TempDir(), "out.txt"), -21, 0)
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+	})
+	t.Run("range", func(t *testing.T) {
+		require.Equal(t, data[2:5], copied(t, 0, 0, WithRange(Range{Start: 2, End: 5})))
+		require.Equal(t, data[15:], copied(t, 0, 0, WithRange(Range{Start: -5})))
+		require.Equal(t, data[5:18], copied(t, 0, 0, WithRange(Range{Start: 5, End: -2})))
+		require.Equal(t, data[16:18], copied(t, 0, 0, WithRange(Range{Start: -4, End: -2})))
+		require.Equal(t, data[10:], copied(t, 0, 0, WithRange(Range{Start: 10, End: 1000})))
+	})
+	t.Run("range errors", func(t *testing.T) {
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		err := Copy(fromFile, toFile, 0, 0, WithRange(Range{Start: 21}))
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+		err = Copy(fromFile, toFile, 0, 0, WithRange(Range{Start: -21}))
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+		err = Copy(fromFile, toFile, 0, 0, WithRange(Range{Start: 15, End: -10}))
+		require.ErrorIs(t, err, ErrInvalidRange)
+		err = Copy(fromFile, toFile, 1, 0, WithRange(Range{Start: 2}))
+		require.ErrorIs(t, err, ErrInvalidRange)
+	})
+	t.Run("stream", func(t *testing.T) {
+		from, err := os.Open(fromFile)
+		require.NoError(t, err)
+		defer from.Close()
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+		go func() {
+			_, _ = from.WriteTo(w)
+			_ = w.Close()
+		}()
+		withStdio(t, &os.Stdin, r)
+
+		err = Copy(stdPath, filepath.Join(t.TempDir(), "out.txt"), -5, 0)
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+		err = Copy(stdPath, filepath.Join(t.TempDir(), "out.txt"), 0, 0, WithRange(Range{End: -5}))
+		require.ErrorIs(t, err, ErrUnsupportedSeek)
+
+		toFile := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, Copy(stdPath, toFile, 0, 0, WithRange(Range{Start: 3, End: 7})))
+		actual, err := os.ReadFile(toFile)
+		require.NoError(t, err)
+		require.Equal(t, data[3:7], actual)
+	})
+}
@@ -21,5 +21,31 @@ cmp out.txt testdata/out_offset100_limit1000.txt
 ./go-cp -from testdata/input.txt -to out.txt -offset 6000 -limit 1000
 cmp out.txt testdata/out_offset6000_limit1000.txt
 
-rm -f go-cp out.txt
+cat testdata/input.txt | ./go-cp -from - -to out.txt -offset 100 -limit 1000
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to - -offset 6000 -limit 1000 > out.txt
+cmp out.txt testdata/out_offset6000_limit1000.txt
+
+./go-cp -from testdata/input.txt -to - -offset 6000 -limit 1000 -verify sha256 2> /dev/null > out.txt
+cmp out.txt testdata/out_offset6000_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt -range 100:1100
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt -offset 100 -limit 1000 -parallel 4
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata -to out -recursive
+diff -r out testdata
+
+if ./go-cp -from testdata/missing.txt -to out.txt 2> /dev/null; then
+  exit 1
+fi
+
+if ./go-cp -from testdata -to out -recursive -limit 10 2> /dev/null; then
+  exit 1
+fi
+
+rm -rf go-cp out.txt out
 echo "PASS"